@@ -0,0 +1,78 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+import "strings"
+
+// ALALC returns a configuration for ALA-LC romanization, as used in library
+// catalog records: modified Hepburn with macrons for long vowels, an apostrophe
+// after a syllabic n before a vowel or y, particles written phonetically as
+// separate words, Japanese punctuation replaced by its Western form, and the
+// first word of each sentence capitalized.
+//
+// ALA-LC divides text into words and hyphenates compounds. The segment
+// function, if not nil, does that division; see RomajiConfig.Segment.
+// Without it, words are divided only around particles.
+func ALALC(segment func(kana string) []string) *RomajiConfig {
+	return &RomajiConfig{
+		System:      Hepburn,
+		LongVowel:   Macron,
+		NSeparator:  "'",
+		Boundary:    BoundarySmart,
+		Particles:   true,
+		Segment:     segment,
+		Capitalize:  true,
+		Punctuation: ALALCPunctuation,
+	}
+}
+
+// ALALCPunctuation maps Japanese punctuation to the punctuation used in
+// ALA-LC romanization.
+var ALALCPunctuation = map[rune]string{
+	'。': ".",
+	'、': ",",
+	'「': "“",
+	'」': "”",
+	'『': "“",
+	'』': "”",
+	'・': "-",
+	'！': "!",
+	'？': "?",
+}
+
+// putWords writes a run of kana divided into words by the configured
+// Segment function. It reports false, having written nothing, if the
+// words do not spell out the run.
+func (r *romaji) putWords(run []rune) bool {
+	words := r.cfg.Segment(string(run))
+	if strings.Replace(strings.Join(words, ""), "-", "", -1) != string(run) {
+		return false
+	}
+	sys := r.cfg.System.romanization()
+	sep := false
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+		if sep {
+			r.t.put(' ')
+		}
+		sep = true
+		for i, part := range strings.Split(word, "-") {
+			if i > 0 {
+				r.t.put('-')
+			}
+			if part == "" {
+				continue
+			}
+			syls := sys.syllables([]rune(part))
+			if p, ok := particles[part]; ok && r.cfg.Particles {
+				syls[0].roman = p
+			}
+			r.putRun(syls)
+		}
+	}
+	return true
+}
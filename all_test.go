@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 type testPair struct {
@@ -51,6 +53,17 @@ var romajiTests = []testPair{
 	{"カタカナ", "katakana"},
 	// Leave non-kana alone.
 	{"a日本語ひらがなカタカナb\n", "a日本語 hiraganakatakana b\n"},
+	// Sokuon.
+	{"きって", "kitte"},
+	{"まっちゃ", "matcha"},
+	{"ラッシュ", "rasshu"},
+	{"がっこう", "gakkou"},
+	{"あっ", "a'"},
+	{"っぽい", "ppoi"},
+	// Prolonged sound mark.
+	{"コーヒー", "koohii"},
+	{"ラーメン", "raamen"},
+	{"ンー", "n-"},
 }
 
 func TestRomaji(t *testing.T) {
@@ -62,13 +75,282 @@ func TestRomaji(t *testing.T) {
 	}
 }
 
+var systemTests = []struct {
+	system System
+	testPair
+}{
+	{Hepburn, testPair{"ふじさんのちしつちょうさ", "fujisannochishitsuchousa"}},
+	{Kunrei, testPair{"ふじさんのちしつちょうさ", "huzisannotisitutyousa"}},
+	{Nihon, testPair{"ふじさんのちしつちょうさ", "huzisannotisitutyousa"}},
+	{Hepburn, testPair{"ぢづぢゃをゐ", "jizujawowi"}},
+	{Kunrei, testPair{"ぢづぢゃをゐ", "zizuzyaoi"}},
+	{Nihon, testPair{"ぢづぢゃをゐ", "didudyawowi"}},
+	{Hepburn, testPair{"キャクシャジュンバン", "kyakushajunban"}},
+	{Kunrei, testPair{"キャクシャジュンバン", "kyakusyazyunban"}},
+	{Kunrei, testPair{"まっちゃ", "mattya"}},
+	{Hepburn, testPair{"シェフのティーカップ", "shefunotiikappu"}},
+	{Kunrei, testPair{"シェフのフォーク", "syehunofooku"}},
+	{Kunrei, testPair{"シェフのティーカップ", "syehunothiikappu"}},
+	{Nihon, testPair{"ディズニーのトゥーン", "dhizuniinotwuun"}},
+	{Nihon, testPair{"ジェット", "zyetto"}},
+	{Nihon, testPair{"くゎし", "kwasi"}},
+}
+
+func TestRomajiSystem(t *testing.T) {
+	for i, test := range systemTests {
+		name := fmt.Sprintf("#%d: romaji %s:", i, test.system)
+		cfg := &RomajiConfig{System: test.system}
+		testString(name, t, test.testPair, cfg.RomajiString)
+		testBytes(name, t, test.testPair, cfg.Romaji)
+		testReader(name, t, test.testPair, cfg.RomajiReader)
+	}
+	cfg := &RomajiConfig{System: Kunrei, LongVowel: Circumflex}
+	testString("romaji Kunrei circumflex:", t, testPair{"パーティー", "pâthî"}, cfg.RomajiString)
+}
+
+var longVowelTests = []struct {
+	long LongVowel
+	testPair
+}{
+	{AsWritten, testPair{"とうきょう", "toukyou"}},
+	{Macron, testPair{"とうきょう", "tōkyō"}},
+	{Circumflex, testPair{"とうきょう", "tôkyô"}},
+	{Doubled, testPair{"とうきょう", "tookyoo"}},
+	{Oh, testPair{"とうきょう", "tohkyoh"}},
+	{Macron, testPair{"おおさか", "ōsaka"}},
+	{Macron, testPair{"くうき", "kūki"}},
+	{Macron, testPair{"おかあさん", "okāsan"}},
+	{Macron, testPair{"コーヒー", "kōhī"}},
+	{Macron, testPair{"せんせい", "sensei"}},
+	{Macron, testPair{"おにいさん", "oniisan"}},
+	{Oh, testPair{"ゆうこ", "yuko"}},
+	{Doubled, testPair{"スーパー", "suupaa"}},
+	{AsWritten, testPair{"パーティー", "paatii"}},
+	{Macron, testPair{"パーティー", "pātī"}},
+	{AsWritten, testPair{"フォーク", "fooku"}},
+	{Macron, testPair{"フォーク", "fōku"}},
+	{Macron, testPair{"ジェット", "jetto"}},
+	{Macron, testPair{"ヴァイオリン", "vaiorin"}},
+	// No spelling of its own; the long vowel is still the small one.
+	{AsWritten, testPair{"テァー", "te-a"}},
+}
+
+func TestRomajiLongVowel(t *testing.T) {
+	for i, test := range longVowelTests {
+		name := fmt.Sprintf("#%d: romaji long vowel:", i)
+		cfg := &RomajiConfig{LongVowel: test.long}
+		testString(name, t, test.testPair, cfg.RomajiString)
+		testBytes(name, t, test.testPair, cfg.Romaji)
+		testReader(name, t, test.testPair, cfg.RomajiReader)
+	}
+}
+
+var syllabicNTests = []struct {
+	cfg RomajiConfig
+	testPair
+}{
+	{RomajiConfig{}, testPair{"きんえん", "kinen"}},
+	{RomajiConfig{NSeparator: "'"}, testPair{"きんえん", "kin'en"}},
+	{RomajiConfig{NSeparator: "'"}, testPair{"きねん", "kinen"}},
+	{RomajiConfig{NSeparator: "'"}, testPair{"かんい", "kan'i"}},
+	{RomajiConfig{NSeparator: "-"}, testPair{"ほんや", "hon-ya"}},
+	{RomajiConfig{NSeparator: "'"}, testPair{"こんにちは", "konnichiha"}},
+	{RomajiConfig{AssimilateN: true}, testPair{"しんぶん", "shimbun"}},
+	{RomajiConfig{AssimilateN: true}, testPair{"さんま", "samma"}},
+	{RomajiConfig{AssimilateN: true}, testPair{"せんぱい", "sempai"}},
+	{RomajiConfig{AssimilateN: true}, testPair{"てんき", "tenki"}},
+}
+
+func TestRomajiSyllabicN(t *testing.T) {
+	for i, test := range syllabicNTests {
+		name := fmt.Sprintf("#%d: romaji syllabic n:", i)
+		testString(name, t, test.testPair, test.cfg.RomajiString)
+		testBytes(name, t, test.testPair, test.cfg.Romaji)
+		testReader(name, t, test.testPair, test.cfg.RomajiReader)
+	}
+}
+
+var boundaryTests = []struct {
+	cfg RomajiConfig
+	testPair
+}{
+	{RomajiConfig{}, testPair{"a日本語ひらがなカタカナb", "a日本語 hiraganakatakana b"}},
+	{RomajiConfig{Boundary: BoundaryNone}, testPair{"a日本語ひらがなカタカナb", "a日本語hiraganakatakanab"}},
+	{RomajiConfig{Boundary: BoundaryNone}, testPair{"ポケモンGO", "pokemonGO"}},
+	{RomajiConfig{Boundary: BoundaryDelimiter, Delimiter: "|"}, testPair{"日本語ひらがな。", "日本語|hiragana|。"}},
+	{RomajiConfig{Boundary: BoundarySmart}, testPair{"ポケモンGO", "pokemon GO"}},
+	{RomajiConfig{Boundary: BoundarySmart}, testPair{"「ひらがな」、1かい", "「hiragana」、1kai"}},
+	{RomajiConfig{Boundary: BoundarySmart}, testPair{"http://example.com/カタカナ/", "http://example.com/katakana/"}},
+	{RomajiConfig{Boundary: BoundarySmart}, testPair{"日本語ひらがな", "日本語 hiragana"}},
+}
+
+func TestRomajiBoundary(t *testing.T) {
+	for i, test := range boundaryTests {
+		name := fmt.Sprintf("#%d: romaji boundary:", i)
+		testString(name, t, test.testPair, test.cfg.RomajiString)
+		testBytes(name, t, test.testPair, test.cfg.Romaji)
+		testReader(name, t, test.testPair, test.cfg.RomajiReader)
+	}
+}
+
+var particleTests = []struct {
+	cfg RomajiConfig
+	testPair
+}{
+	{RomajiConfig{}, testPair{"わたしはがくせいです", "watashihagakuseidesu"}},
+	{RomajiConfig{Particles: true}, testPair{"わたしはがくせいです", "watashi wa gakuseidesu"}},
+	{RomajiConfig{Particles: true}, testPair{"きょうはいいてんきですね", "kyou wa iitenkidesune"}},
+	{RomajiConfig{Particles: true}, testPair{"がっこうへいく", "gakkou e iku"}},
+	{RomajiConfig{Particles: true, Words: map[string]bool{}}, testPair{"わたしはがくせいです", "watashihagakuseidesu"}},
+	{RomajiConfig{Particles: true, Words: map[string]bool{"がくせい": true}}, testPair{"がくせいはわたし", "gakusei wa watashi"}},
+	{RomajiConfig{Particles: true}, testPair{"私は学生です", "私 wa 学生 desu"}},
+	{RomajiConfig{Particles: true}, testPair{"東京へいきます", "東京 e ikimasu"}},
+	{RomajiConfig{Particles: true}, testPair{"ほんをよむ", "hon o yomu"}},
+	{RomajiConfig{Particles: true}, testPair{"これはペンです", "kore wa pendesu"}},
+	{RomajiConfig{Particles: true}, testPair{"こんにちは", "konnichiwa"}},
+	{RomajiConfig{Particles: true}, testPair{"こんばんはやまださん", "konbanwayamadasan"}},
+	{RomajiConfig{Particles: true}, testPair{"はは", "haha"}},
+	{RomajiConfig{Particles: true}, testPair{"ははは", "hahaha"}},
+	{RomajiConfig{Particles: true}, testPair{"母は", "母 wa"}},
+	{RomajiConfig{Particles: true}, testPair{"日本はじめて", "日本 hajimete"}},
+	{RomajiConfig{Particles: true}, testPair{"日本はひろい", "日本 wa hiroi"}},
+	{RomajiConfig{Particles: true}, testPair{"ここでは", "kokode wa"}},
+	{RomajiConfig{Particles: true}, testPair{"はなび", "hanabi"}},
+	{RomajiConfig{Particles: true}, testPair{"へいわ", "heiwa"}},
+	{RomajiConfig{Particles: true, LongVowel: Macron}, testPair{"東京へえき", "東京 e eki"}},
+}
+
+func TestRomajiParticles(t *testing.T) {
+	for i, test := range particleTests {
+		name := fmt.Sprintf("#%d: romaji particles:", i)
+		testString(name, t, test.testPair, test.cfg.RomajiString)
+		testBytes(name, t, test.testPair, test.cfg.Romaji)
+		testReader(name, t, test.testPair, test.cfg.RomajiReader)
+	}
+}
+
+var alalcTests = []testPair{
+	{"とうきょう", "Tōkyō"},
+	{"しんぶんをよむ。東京へいく。", "Shinbun o yomu. 東京 e iku."},
+	{"ほんや、しんぶん。", "Hon'ya, shinbun."},
+	{"「ほんや」をよむ。", "“Hon'ya” o yomu."},
+	{"とうきょう「ほんや」。", "Tōkyō “hon'ya”."},
+	{"ジョン・スミス。", "Jon-sumisu."},
+	{"ほんや", "Hon'ya"},
+	{"とうきょうとのぶんか", "Tōkyō-to no bunka"},
+	{"わたしはがくせいです", "Watashi wa gakusei desu"},
+	{"ぶんかじんるいがく", "Bunka jinruigaku"},
+}
+
+func alalcSegment(kana string) []string {
+	switch kana {
+	case "とうきょうとのぶんか":
+		return []string{"とうきょう-と", "の", "ぶんか"}
+	case "わたしはがくせいです":
+		return []string{"わたし", "は", "がくせい", "です"}
+	case "ぶんかじんるいがく":
+		return []string{"ぶんか", "じんるいがく"}
+	case "しんぶんをよむ":
+		return []string{"wrong"}
+	}
+	return nil
+}
+
+func TestALALC(t *testing.T) {
+	cfg := ALALC(alalcSegment)
+	for i, test := range alalcTests {
+		name := fmt.Sprintf("#%d: ALA-LC:", i)
+		testString(name, t, test, cfg.RomajiString)
+		testBytes(name, t, test, cfg.Romaji)
+		testReader(name, t, test, cfg.RomajiReader)
+	}
+}
+
+var wapuroTests = []testPair{
+	{"かんい", "kan'i"},
+	{"かに", "kani"},
+	{"こんにちは", "konnnichiha"},
+	{"きって", "kixtute"},
+	{"ちゃ", "cha"},
+	{"ぢゃ", "dixya"},
+	{"ゃ", "xya"},
+	{"とうきょう", "toukyou"},
+	{"を", "wo"},
+	{"ファン", "fuxann"},
+	{"コーヒー", "ko-hi-"},
+	{"ンー", "nnー"},
+	{"日本語ひらがな", "日本語hiragana"},
+}
+
+func TestWapuro(t *testing.T) {
+	cfg := &RomajiConfig{System: Wapuro}
+	for i, test := range wapuroTests {
+		name := fmt.Sprintf("#%d: wapuro:", i)
+		testString(name, t, test, cfg.RomajiString)
+		testBytes(name, t, test, cfg.Romaji)
+		testReader(name, t, test, cfg.RomajiReader)
+	}
+}
+
+// TestWapuroRoundTrip checks that Hiragana and Katakana undo Wapuro
+// romanization of every pair of kana.
+func TestWapuroRoundTrip(t *testing.T) {
+	cfg := &RomajiConfig{System: Wapuro}
+	var kana []rune
+	for r := 'ぁ'; r <= 'ゖ'; r++ {
+		kana = append(kana, r)
+	}
+	for _, r1 := range kana {
+		for _, r2 := range kana {
+			h := string([]rune{r1, r2, 'あ', r1, r2})
+			if got := HiraganaString(cfg.RomajiString(h)); got != h {
+				t.Errorf("hiragana %q: romaji %q gives %q", h, cfg.RomajiString(h), got)
+			}
+			k := string([]rune{r1 + 0x60, r2 + 0x60, 'ア', 'ー', r1 + 0x60, r2 + 0x60, 'ー'})
+			if got := KatakanaString(cfg.RomajiString(k)); got != k {
+				t.Errorf("katakana %q: romaji %q gives %q", k, cfg.RomajiString(k), got)
+			}
+		}
+	}
+	// Iteration marks and half-width katakana are left as they are.
+	for _, h := range []string{"こゝろ", "いすゞ", "ｶﾀｶﾅ", "ﾊﾟﾝ"} {
+		if got := HiraganaString(cfg.RomajiString(h)); got != h {
+			t.Errorf("%q: romaji %q gives %q", h, cfg.RomajiString(h), got)
+		}
+	}
+}
+
+var katakanaMarkTests = []struct {
+	cfg RomajiConfig
+	testPair
+}{
+	{RomajiConfig{}, testPair{"ひらがなカタカナ", "hiraganakatakana"}},
+	{RomajiConfig{KatakanaMark: KatakanaUpper}, testPair{"ひらがなカタカナ", "hiraganaKATAKANA"}},
+	{RomajiConfig{KatakanaMark: KatakanaUpper}, testPair{"コーヒーをのむ", "KOOHIIwonomu"}},
+	{RomajiConfig{KatakanaMark: KatakanaUpper, LongVowel: Macron}, testPair{"コーヒー", "KŌHĪ"}},
+	{RomajiConfig{KatakanaMark: KatakanaDelimit, KatakanaOpen: "[", KatakanaClose: "]"}, testPair{"ひらがなカタカナ", "hiragana[katakana]"}},
+	{RomajiConfig{KatakanaMark: KatakanaDelimit, KatakanaOpen: "[", KatakanaClose: "]"}, testPair{"アのア日本", "[a]no[a] 日本"}},
+	{RomajiConfig{KatakanaMark: KatakanaDelimit, KatakanaOpen: "[", KatakanaClose: "]", Particles: true}, testPair{"これはペンです", "kore wa [pen]desu"}},
+	{RomajiConfig{KatakanaMark: KatakanaDelimit, KatakanaOpen: "[", KatakanaClose: "]", Particles: true}, testPair{"ペンはこれ", "[pen] wa kore"}},
+	{RomajiConfig{KatakanaMark: KatakanaUpper, System: Wapuro}, testPair{"コーヒーをのむ", "KO-HI-wonomu"}},
+}
+
+func TestRomajiKatakanaMark(t *testing.T) {
+	for i, test := range katakanaMarkTests {
+		name := fmt.Sprintf("#%d: romaji katakana mark:", i)
+		testString(name, t, test.testPair, test.cfg.RomajiString)
+		testBytes(name, t, test.testPair, test.cfg.Romaji)
+		testReader(name, t, test.testPair, test.cfg.RomajiReader)
+	}
+}
+
 var hiraganaTests = []testPair{
 	// Unchanged.
 	{"", ""},
 	{"xxqq", "xxqq"},
 	{"hiragana", "ひらがな"},
 	// Multi-kana inputs.
-	{"chachodhowi", "ちゃちょぢょうぃ"},
+	{"chachodhowi", "ちゃちょでょうぃ"},
 	// Consonant modifier (tsu).
 	{"tcho", "っちょ"},
 	{"atcho", "あっちょ"},
@@ -84,6 +366,10 @@ var hiraganaTests = []testPair{
 	// Small kana
 	{"delelyala", "でぇゃぁ"},
 	{"lyululyoololi", "ゅぅょおぉぃ"},
+	// Case.
+	{"Tokyo", "ときょ"},
+	{"KATAKANA", "かたかな"},
+	{"SHUPPATSU", "しゅっぱつ"},
 }
 
 func TestHiragana(t *testing.T) {
@@ -101,7 +387,7 @@ var katakanaTests = []testPair{
 	{"xxqq", "xxqq"},
 	{"katakana", "カタカナ"},
 	// Multi-kana inputs.
-	{"chachodhowi", "チャチョヂョウィ"},
+	{"chachodhowi", "チャチョデョウィ"},
 	// Consonant modifier (tsu).
 	{"tcho", "ッチョ"},
 	{"atcho", "アッチョ"},
@@ -111,12 +397,20 @@ var katakanaTests = []testPair{
 	{"syuppatsu", "シュッパツ"},
 	// Leave existing kana alone.
 	{"カタカナ日本語カタカナひらがなカタカナ\n", "カタカナ日本語カタカナひらがなカタカナ\n"},
+	// Prolonged sound mark.
+	{"ko-hi-", "コーヒー"},
+	{"koohii", "コオヒイ"},
+	{"ra-men", "ラーメン"},
 	//Hepburn / Kunrei
 	{"fujisannochishitsuchousa", "フジサンノチシツチョウサ"},
 	{"huzisannotisitutyousa", "フジサンノチシツチョウサ"},
 	// Small kana
 	{"delelyala", "デェャァ"},
 	{"lyululyoololi", "ュゥョオォィ"},
+	// Case.
+	{"Tokyo", "トキョ"},
+	{"KATAKANA", "カタカナ"},
+	{"XQ", "XQ"},
 }
 
 func TestKatakana(t *testing.T) {
@@ -127,3 +421,656 @@ func TestKatakana(t *testing.T) {
 		testReader(name, t, test, KatakanaReader)
 	}
 }
+
+var mixedScriptTests = []testPair{
+	{"hiraganaKATAKANA", "ひらがなカタカナ"},
+	{"KOOHIIwonomu", "コオヒイをのむ"},
+	{"KO-HI-wonomu", "コーヒーをのむ"},
+	{"kore wa PEN desu", "これ わ ペン です"},
+	{"BETTO", "ベット"},
+	{"kitte", "きって"},
+	{"NNkan'I", "ンかんイ"},
+}
+
+func TestMixedScript(t *testing.T) {
+	cfg := &KanaConfig{MixedScript: true}
+	for i, test := range mixedScriptTests {
+		name := fmt.Sprintf("#%d: mixed script:", i)
+		testString(name, t, test, cfg.HiraganaString)
+		testBytes(name, t, test, cfg.Hiragana)
+		testReader(name, t, test, cfg.HiraganaReader)
+		testString(name, t, test, cfg.KatakanaString)
+		testBytes(name, t, test, cfg.Katakana)
+		testReader(name, t, test, cfg.KatakanaReader)
+	}
+}
+
+// TestMixedScriptRoundTrip checks that mixed-script input undoes Wapuro
+// romanization that marks katakana in upper case.
+func TestMixedScriptRoundTrip(t *testing.T) {
+	romaji := &RomajiConfig{System: Wapuro, KatakanaMark: KatakanaUpper}
+	kana := &KanaConfig{MixedScript: true}
+	for _, x := range []string{"コーヒーをのむ", "ひらがなカタカナ", "ラッシュアワーのでんしゃ", "カンイなきんえん"} {
+		if got := kana.HiraganaString(romaji.RomajiString(x)); got != x {
+			t.Errorf("%q: romaji %q gives %q", x, romaji.RomajiString(x), got)
+		}
+	}
+}
+
+var longVowelInputTests = []struct {
+	cfg      KanaConfig
+	in       string
+	hiragana string
+	katakana string
+}{
+	{KanaConfig{}, "tōkyō", "とうきょう", "トーキョー"},
+	{KanaConfig{}, "kōhī", "こうひい", "コーヒー"},
+	{KanaConfig{}, "ko-hi-", "こ-ひ-", "コーヒー"},
+	{KanaConfig{}, "to\u0304kyo\u0304", "とうきょう", "トーキョー"},
+	{KanaConfig{}, "Tôkyô", "とうきょう", "トーキョー"},
+	{KanaConfig{}, "ōsaka", "おうさか", "オーサカ"},
+	{KanaConfig{DoubleO: true}, "ōsaka", "おおさか", "オーサカ"},
+	{KanaConfig{}, "okāsan", "おかあさん", "オカーサン"},
+	{KanaConfig{}, "ūmi ē", "ううみ ええ", "ウーミ エー"},
+	{KanaConfig{MixedScript: true}, "KŌHĪwonomu", "コーヒーをのむ", "コーヒーをのむ"},
+	{KanaConfig{MixedScript: true}, "tōkyō", "とうきょう", "とうきょう"},
+	{KanaConfig{}, "naïve é", "なïゔぇ é", "ナïヴェ é"},
+}
+
+func TestLongVowelInput(t *testing.T) {
+	for i, test := range longVowelInputTests {
+		name := fmt.Sprintf("#%d: long vowel input:", i)
+		testString(name, t, testPair{test.in, test.hiragana}, test.cfg.HiraganaString)
+		testBytes(name, t, testPair{test.in, test.hiragana}, test.cfg.Hiragana)
+		testReader(name, t, testPair{test.in, test.hiragana}, test.cfg.HiraganaReader)
+		testString(name, t, testPair{test.in, test.katakana}, test.cfg.KatakanaString)
+		testBytes(name, t, testPair{test.in, test.katakana}, test.cfg.Katakana)
+		testReader(name, t, testPair{test.in, test.katakana}, test.cfg.KatakanaReader)
+	}
+}
+
+var syllabicNInputTests = []testPair{
+	{"kani", "かに"},
+	{"kan'i", "かんい"},
+	{"kanni", "かんに"},
+	{"kannni", "かんに"},
+	{"kaxni", "かんい"},
+	{"kinen", "きねん"},
+	{"kin'en", "きんえん"},
+	{"kinnen", "きんねん"},
+	{"hon'ya", "ほんや"},
+	{"honnya", "ほんにゃ"},
+	{"kanji", "かんじ"},
+	{"kannji", "かんじ"},
+	{"kon'nichiha", "こんにちは"},
+	{"konnnichiha", "こんにちは"},
+	{"konnichiha", "こんにちは"},
+	{"san", "さん"},
+	{"sann", "さん"},
+	{"san'", "さん"},
+	{"sannn", "さんん"},
+	{"n'", "ん"},
+	{"'n'", "'ん"},
+}
+
+func TestSyllabicNInput(t *testing.T) {
+	for i, test := range syllabicNInputTests {
+		name := fmt.Sprintf("#%d: syllabic n:", i)
+		testString(name, t, test, HiraganaString)
+		testBytes(name, t, test, Hiragana)
+		testReader(name, t, test, HiraganaReader)
+	}
+}
+
+var punctuationTests = []struct {
+	cfg KanaConfig
+	testPair
+}{
+	{KanaConfig{}, testPair{"sou desu ka?", "そう です か?"}},
+	{KanaConfig{Punctuation: IMEPunctuation}, testPair{"sou desu ka?", "そう です か？"}},
+	{KanaConfig{Punctuation: IMEPunctuation, DropSpaces: true}, testPair{"sou desu ka?", "そうですか？"}},
+	{KanaConfig{Punctuation: IMEPunctuation, DropSpaces: true}, testPair{"[hai], so-desu. a/b~c!", "「はい」、そーです。あ・b〜c！"}},
+	{KanaConfig{Punctuation: IMEPunctuation, DropSpaces: true}, testPair{"xx de", "xx で"}},
+	{KanaConfig{Punctuation: IMEPunctuation, DropSpaces: true, Widen: true}, testPair{"xx de 3 ji", "ｘｘ　で３　じ"}},
+	{KanaConfig{Punctuation: map[rune]string{'.': "．"}}, testPair{"sou.", "そう．"}},
+	{KanaConfig{Widen: true}, testPair{"日本 kk", "日本　ｋｋ"}},
+}
+
+func TestPunctuation(t *testing.T) {
+	for i, test := range punctuationTests {
+		name := fmt.Sprintf("#%d: punctuation:", i)
+		testString(name, t, test.testPair, test.cfg.HiraganaString)
+		testBytes(name, t, test.testPair, test.cfg.Hiragana)
+		testReader(name, t, test.testPair, test.cfg.HiraganaReader)
+	}
+}
+
+var escapeTests = []struct {
+	cfg KanaConfig
+	testPair
+}{
+	{KanaConfig{}, testPair{"tsugi no meeting wa {Zoom} de", "つぎ の めえちんg わ {ぞおm} で"}},
+	{KanaConfig{EscapeOpen: '{', EscapeClose: '}'}, testPair{"tsugi no meeting wa {Zoom} de", "つぎ の めえちんg わ Zoom で"}},
+	{KanaConfig{EscapeOpen: '`', EscapeClose: '`'}, testPair{"`Zoom`de`ka`", "Zoomでka"}},
+	{KanaConfig{EscapeOpen: '「', EscapeClose: '」'}, testPair{"「Zoom」de", "Zoomで"}},
+	{KanaConfig{EscapeOpen: '{', EscapeClose: '}'}, testPair{"kk{ka}", "kkka"}},
+	{KanaConfig{EscapeOpen: '{', EscapeClose: '}'}, testPair{"ka{kōhī", "かkōhī"}},
+	{KanaConfig{EscapeOpen: '{', EscapeClose: '}', Punctuation: IMEPunctuation, Widen: true}, testPair{"{a.b}a.b", "a.bあ。ｂ"}},
+}
+
+func TestEscape(t *testing.T) {
+	for i, test := range escapeTests {
+		name := fmt.Sprintf("#%d: escape:", i)
+		testString(name, t, test.testPair, test.cfg.HiraganaString)
+		testBytes(name, t, test.testPair, test.cfg.Hiragana)
+		testReader(name, t, test.testPair, test.cfg.HiraganaReader)
+		testReader(name, t, test.testPair, func(r io.Reader) io.Reader {
+			return test.cfg.HiraganaReader(iotest.OneByteReader(r))
+		})
+	}
+}
+
+var strictTests = []struct {
+	cfg KanaConfig
+	testPair
+}{
+	{KanaConfig{}, testPair{"now is the time", "のw いs てぇ ちめ"}},
+	{KanaConfig{Strict: true}, testPair{"now is the time", "now is てぇ ちめ"}},
+	{KanaConfig{Strict: true}, testPair{"good morning", "good morning"}},
+	{KanaConfig{Strict: true}, testPair{"ashita wa meeting desu", "あした わ meeting です"}},
+	{KanaConfig{Strict: true}, testPair{"ok, ashita ne!", "ok, あした ね!"}},
+	{KanaConfig{Strict: true}, testPair{"kitte matcha kk", "きって まっちゃ kk"}},
+	{KanaConfig{Strict: true}, testPair{"kan'i dan'", "かんい だん"}},
+	{KanaConfig{Strict: true}, testPair{"ko-hi- 'hai'", "ko-hi- 'はい'"}},
+	{KanaConfig{Strict: true, Punctuation: IMEPunctuation}, testPair{"ko-hi-.", "こーひー。"}},
+	{KanaConfig{Strict: true}, testPair{"3ji ni", "3じ に"}},
+	{KanaConfig{Strict: true}, testPair{"bkatta", "bkatta"}},
+}
+
+func TestStrict(t *testing.T) {
+	for i, test := range strictTests {
+		name := fmt.Sprintf("#%d: strict:", i)
+		testString(name, t, test.testPair, test.cfg.HiraganaString)
+		testBytes(name, t, test.testPair, test.cfg.Hiragana)
+		testReader(name, t, test.testPair, test.cfg.HiraganaReader)
+	}
+	cfg := &KanaConfig{Strict: true}
+	testString("strict katakana", t, testPair{"ko-hi- desu", "コーヒー デス"}, cfg.KatakanaString)
+}
+
+// imeTests holds every romaji spelling accepted by the Microsoft and Google
+// input methods, apart from their symbols, with its hiragana.
+var imeTests = []testPair{
+	{"a", "あ"}, {"i", "い"}, {"u", "う"}, {"e", "え"}, {"o", "お"},
+	{"yi", "い"}, {"ye", "いぇ"}, {"wu", "う"},
+
+	{"xa", "ぁ"}, {"xi", "ぃ"}, {"xu", "ぅ"}, {"xe", "ぇ"}, {"xo", "ぉ"},
+	{"la", "ぁ"}, {"li", "ぃ"}, {"lu", "ぅ"}, {"le", "ぇ"}, {"lo", "ぉ"},
+	{"xyi", "ぃ"}, {"xye", "ぇ"}, {"lyi", "ぃ"}, {"lye", "ぇ"},
+
+	{"xya", "ゃ"}, {"xyu", "ゅ"}, {"xyo", "ょ"}, {"lya", "ゃ"}, {"lyu", "ゅ"},
+	{"lyo", "ょ"},
+
+	{"xtu", "っ"}, {"ltu", "っ"}, {"xtsu", "っ"}, {"ltsu", "っ"}, {"xwa", "ゎ"},
+	{"lwa", "ゎ"}, {"xka", "ゕ"}, {"lka", "ゕ"}, {"xke", "ゖ"}, {"lke", "ゖ"},
+	{"xn", "ん"},
+
+	{"ka", "か"}, {"ki", "き"}, {"ku", "く"}, {"ke", "け"}, {"ko", "こ"},
+	{"ca", "か"}, {"ci", "し"}, {"cu", "く"}, {"ce", "せ"}, {"co", "こ"},
+	{"kya", "きゃ"}, {"kyi", "きぃ"}, {"kyu", "きゅ"}, {"kye", "きぇ"}, {"kyo", "きょ"},
+
+	{"kwa", "くぁ"}, {"kwi", "くぃ"}, {"kwu", "くぅ"}, {"kwe", "くぇ"}, {"kwo", "くぉ"},
+	{"qa", "くぁ"}, {"qi", "くぃ"}, {"qu", "く"}, {"qe", "くぇ"}, {"qo", "くぉ"},
+	{"qwa", "くぁ"}, {"qwi", "くぃ"}, {"qwu", "くぅ"}, {"qwe", "くぇ"}, {"qwo", "くぉ"},
+	{"qya", "くゃ"}, {"qyu", "くゅ"}, {"qyo", "くょ"},
+
+	{"ga", "が"}, {"gi", "ぎ"}, {"gu", "ぐ"}, {"ge", "げ"}, {"go", "ご"},
+	{"gya", "ぎゃ"}, {"gyi", "ぎぃ"}, {"gyu", "ぎゅ"}, {"gye", "ぎぇ"}, {"gyo", "ぎょ"},
+	{"gwa", "ぐぁ"}, {"gwi", "ぐぃ"}, {"gwu", "ぐぅ"}, {"gwe", "ぐぇ"}, {"gwo", "ぐぉ"},
+
+	{"sa", "さ"}, {"si", "し"}, {"su", "す"}, {"se", "せ"}, {"so", "そ"},
+	{"shi", "し"}, {"sha", "しゃ"}, {"shu", "しゅ"}, {"she", "しぇ"}, {"sho", "しょ"},
+	{"sya", "しゃ"}, {"syi", "しぃ"}, {"syu", "しゅ"}, {"sye", "しぇ"}, {"syo", "しょ"},
+	{"swa", "すぁ"}, {"swi", "すぃ"}, {"swu", "すぅ"}, {"swe", "すぇ"}, {"swo", "すぉ"},
+
+	{"za", "ざ"}, {"zi", "じ"}, {"zu", "ず"}, {"ze", "ぜ"}, {"zo", "ぞ"},
+	{"ji", "じ"}, {"ja", "じゃ"}, {"ju", "じゅ"}, {"je", "じぇ"}, {"jo", "じょ"},
+	{"jya", "じゃ"}, {"jyi", "じぃ"}, {"jyu", "じゅ"}, {"jye", "じぇ"}, {"jyo", "じょ"},
+	{"zya", "じゃ"}, {"zyi", "じぃ"}, {"zyu", "じゅ"}, {"zye", "じぇ"}, {"zyo", "じょ"},
+
+	{"ta", "た"}, {"ti", "ち"}, {"tu", "つ"}, {"te", "て"}, {"to", "と"},
+	{"chi", "ち"}, {"tsu", "つ"}, {"cha", "ちゃ"}, {"chu", "ちゅ"}, {"che", "ちぇ"},
+	{"cho", "ちょ"}, {"cya", "ちゃ"}, {"cyi", "ちぃ"}, {"cyu", "ちゅ"}, {"cye", "ちぇ"},
+	{"cyo", "ちょ"}, {"tya", "ちゃ"}, {"tyi", "ちぃ"}, {"tyu", "ちゅ"}, {"tye", "ちぇ"},
+	{"tyo", "ちょ"},
+
+	{"tsa", "つぁ"}, {"tsi", "つぃ"}, {"tse", "つぇ"}, {"tso", "つぉ"}, {"tha", "てゃ"},
+	{"thi", "てぃ"}, {"thu", "てゅ"}, {"the", "てぇ"}, {"tho", "てょ"}, {"twa", "とぁ"},
+	{"twi", "とぃ"}, {"twu", "とぅ"}, {"twe", "とぇ"}, {"two", "とぉ"},
+
+	{"da", "だ"}, {"di", "ぢ"}, {"du", "づ"}, {"de", "で"}, {"do", "ど"},
+	{"dya", "ぢゃ"}, {"dyi", "ぢぃ"}, {"dyu", "ぢゅ"}, {"dye", "ぢぇ"}, {"dyo", "ぢょ"},
+	{"dha", "でゃ"}, {"dhi", "でぃ"}, {"dhu", "でゅ"}, {"dhe", "でぇ"}, {"dho", "でょ"},
+	{"dwa", "どぁ"}, {"dwi", "どぃ"}, {"dwu", "どぅ"}, {"dwe", "どぇ"}, {"dwo", "どぉ"},
+
+	{"na", "な"}, {"ni", "に"}, {"nu", "ぬ"}, {"ne", "ね"}, {"no", "の"},
+	{"n", "ん"}, {"nya", "にゃ"}, {"nyi", "にぃ"}, {"nyu", "にゅ"}, {"nye", "にぇ"},
+	{"nyo", "にょ"},
+
+	{"ha", "は"}, {"hi", "ひ"}, {"hu", "ふ"}, {"he", "へ"}, {"ho", "ほ"},
+	{"fu", "ふ"}, {"hya", "ひゃ"}, {"hyi", "ひぃ"}, {"hyu", "ひゅ"}, {"hye", "ひぇ"},
+	{"hyo", "ひょ"},
+
+	{"fa", "ふぁ"}, {"fi", "ふぃ"}, {"fe", "ふぇ"}, {"fo", "ふぉ"}, {"fwa", "ふぁ"},
+	{"fwi", "ふぃ"}, {"fwu", "ふぅ"}, {"fwe", "ふぇ"}, {"fwo", "ふぉ"}, {"fya", "ふゃ"},
+	{"fyi", "ふぃ"}, {"fyu", "ふゅ"}, {"fye", "ふぇ"}, {"fyo", "ふょ"},
+
+	{"ba", "ば"}, {"bi", "び"}, {"bu", "ぶ"}, {"be", "べ"}, {"bo", "ぼ"},
+	{"bya", "びゃ"}, {"byi", "びぃ"}, {"byu", "びゅ"}, {"bye", "びぇ"}, {"byo", "びょ"},
+
+	{"pa", "ぱ"}, {"pi", "ぴ"}, {"pu", "ぷ"}, {"pe", "ぺ"}, {"po", "ぽ"},
+	{"pya", "ぴゃ"}, {"pyi", "ぴぃ"}, {"pyu", "ぴゅ"}, {"pye", "ぴぇ"}, {"pyo", "ぴょ"},
+
+	{"ma", "ま"}, {"mi", "み"}, {"mu", "む"}, {"me", "め"}, {"mo", "も"},
+	{"mya", "みゃ"}, {"myi", "みぃ"}, {"myu", "みゅ"}, {"mye", "みぇ"}, {"myo", "みょ"},
+
+	{"ya", "や"}, {"yu", "ゆ"}, {"yo", "よ"},
+
+	{"ra", "ら"}, {"ri", "り"}, {"ru", "る"}, {"re", "れ"}, {"ro", "ろ"},
+	{"rya", "りゃ"}, {"ryi", "りぃ"}, {"ryu", "りゅ"}, {"rye", "りぇ"}, {"ryo", "りょ"},
+
+	{"wa", "わ"}, {"wi", "うぃ"}, {"we", "うぇ"}, {"wo", "を"}, {"wha", "うぁ"},
+	{"whi", "うぃ"}, {"whu", "う"}, {"whe", "うぇ"}, {"who", "うぉ"}, {"wyi", "ゐ"},
+	{"wye", "ゑ"},
+
+	{"va", "ゔぁ"}, {"vi", "ゔぃ"}, {"vu", "ゔ"}, {"ve", "ゔぇ"}, {"vo", "ゔぉ"},
+	{"vya", "ゔゃ"}, {"vyi", "ゔぃ"}, {"vyu", "ゔゅ"}, {"vye", "ゔぇ"}, {"vyo", "ゔょ"},
+}
+
+func TestIME(t *testing.T) {
+	if len(imeTests) != len(romajiH) {
+		t.Errorf("%d tests for %d romaji", len(imeTests), len(romajiH))
+	}
+	for _, test := range imeTests {
+		testString("ime hiragana", t, test, HiraganaString)
+		kata := testPair{test.in, katakanaString(test.out)}
+		testString("ime katakana", t, kata, KatakanaString)
+	}
+	// Some loanwords, and spellings of four letters.
+	for _, test := range []testPair{
+		{"tsa-ri", "ツァーリ"},
+		{"thi-shatsu", "ティーシャツ"},
+		{"dhu-ku", "デューク"},
+		{"twu-", "トゥー"},
+		{"kwa", "クァ"},
+		{"whisuki-", "ウィスキー"},
+		{"xtsu", "ッ"},
+		{"ltsu", "ッ"},
+		{"kaxtsuta", "カッタ"},
+	} {
+		testString("ime", t, test, KatakanaString)
+	}
+}
+
+var diagnosticTests = []struct {
+	in    string
+	out   string
+	diags []Diagnostic
+}{
+	{"hiragana", "ひらがな", nil},
+	{"kyx", "kyx", []Diagnostic{{0, "kyx", UnknownSyllable}}},
+	{"qqa", "っくぁ", nil},
+	{"ka q ki", "か q き", []Diagnostic{{3, "q", StrayConsonant}}},
+	{"xyaka", "ゃか", []Diagnostic{{0, "xya", DanglingSmallKana}}},
+	{"kyaxyu", "きゃゅ", nil},
+	{"日本 lyu", "日本 ゅ", []Diagnostic{{7, "lyu", DanglingSmallKana}}},
+	{"kō mm", "こう mm", []Diagnostic{{4, "mm", UnknownSyllable}}},
+	{"ko\u0304 mm", "こう mm", []Diagnostic{{5, "mm", UnknownSyllable}}},
+	{"sh 3 ts", "sh 3 ts", []Diagnostic{{0, "sh", UnknownSyllable}, {5, "ts", UnknownSyllable}}},
+	{"kyx xya", "kyx ゃ", []Diagnostic{{0, "kyx", UnknownSyllable}, {4, "xya", DanglingSmallKana}}},
+	{"q ka", "q か", []Diagnostic{{0, "q", StrayConsonant}}},
+}
+
+func TestDiagnostics(t *testing.T) {
+	cfg := &KanaConfig{}
+	for i, test := range diagnosticTests {
+		out, diags := cfg.HiraganaDiagnostics(test.in)
+		if out != test.out || !reflect.DeepEqual(diags, test.diags) {
+			t.Errorf("#%d: %q: got %q %v; expected %q %v", i, test.in, out, diags, test.out, test.diags)
+		}
+		// The streaming path reports the same.
+		var streamed []Diagnostic
+		rcfg := &KanaConfig{Diagnose: func(d Diagnostic) { streamed = append(streamed, d) }}
+		data, _ := ioutil.ReadAll(rcfg.HiraganaReader(strings.NewReader(test.in)))
+		if string(data) != test.out || !reflect.DeepEqual(streamed, test.diags) {
+			t.Errorf("#%d: reader %q: got %q %v; expected %q %v", i, test.in, data, streamed, test.out, test.diags)
+		}
+	}
+}
+
+var longVowelKatakanaTests = []testPair{
+	{"koohii", "コーヒー"},
+	{"ko-hi-", "コーヒー"},
+	{"suupaa", "スーパー"},
+	{"shiitsu", "シーツ"},
+	{"kou", "コウ"},
+}
+
+func TestKatakanaLongVowels(t *testing.T) {
+	cfg := &KanaConfig{LongVowels: true}
+	for i, test := range longVowelKatakanaTests {
+		name := fmt.Sprintf("#%d: katakana long vowels:", i)
+		testString(name, t, test, cfg.KatakanaString)
+		testBytes(name, t, test, cfg.Katakana)
+		testReader(name, t, test, cfg.KatakanaReader)
+	}
+}
+
+// googleTable is a fragment of a table exported by Google Japanese Input.
+const googleTable = "a\tあ\r\nka\tか\r\nkk\tっ\tk\r\nn\tん\r\nnn\tん\r\n\r\n.\t。\r\n-\tー\r\n"
+
+func TestTable(t *testing.T) {
+	house := DefaultTable()
+	house.Set("KWS", "くわす", "")
+	house.Delete("la")
+	cfg := &KanaConfig{Table: house, Strict: true}
+	for _, test := range []testPair{
+		{"kwsa", "くわすあ"},
+		{"la", "la"},
+		{"kyoto", "きょと"},
+	} {
+		testString("house table", t, test, cfg.HiraganaString)
+		testBytes("house table", t, test, cfg.Hiragana)
+		testReader("house table", t, test, cfg.HiraganaReader)
+	}
+	testString("house table", t, testPair{"kws", "クワス"}, cfg.KatakanaString)
+	if _, _, ok := defaultTable.Lookup("la"); !ok {
+		t.Error("changing a copy of the default table changed the default")
+	}
+
+	google, err := ReadTable(strings.NewReader(googleTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kana, pending, ok := google.Lookup("kk"); kana != "っ" || pending != "k" || !ok {
+		t.Errorf("kk: got %q %q %t; expected \"っ\" \"k\" true", kana, pending, ok)
+	}
+	cfg = &KanaConfig{Table: google, Strict: true}
+	for _, test := range []testPair{
+		{"kakka.", "かっか。"},
+		{"kanna-", "かんあー"},
+		{"ki", "ki"},
+	} {
+		testString("google table", t, test, cfg.HiraganaString)
+		testReader("google table", t, test, cfg.HiraganaReader)
+	}
+	testString("google table", t, testPair{"akka-", "アッカー"}, cfg.KatakanaString)
+	cfg = &KanaConfig{Table: google, MixedScript: true}
+	testString("google table", t, testPair{"AKKA kakka", "アッカ かっか"}, cfg.HiraganaString)
+	testReader("google table", t, testPair{"AKKA kakka", "アッカ かっか"}, cfg.HiraganaReader)
+
+	for _, bad := range []string{"ka\n\tか\n", "ka\tか\tk\tx\n", "ka\n", "kk\tっ\tkk\n"} {
+		if _, err := ReadTable(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestComposer(t *testing.T) {
+	var c Composer
+	check := func(committed, preedit string) {
+		t.Helper()
+		if c.Committed() != committed || c.Preedit() != preedit {
+			t.Errorf("got %q %q; expected %q %q", c.Committed(), c.Preedit(), committed, preedit)
+		}
+	}
+	c.TypeString("k")
+	check("", "k")
+	c.TypeString("y")
+	check("", "ky")
+	c.TypeString("o")
+	check("きょ", "")
+	c.TypeString("ut")
+	check("きょう", "t")
+	c.TypeString("ts")
+	check("きょう", "tts")
+	c.TypeString("u")
+	check("きょうっつ", "")
+	c.TypeString("nn")
+	check("きょうっつ", "nn")
+	c.TypeString("a")
+	check("きょうっつんな", "")
+	c.TypeString("n")
+	check("きょうっつんな", "n")
+	c.Commit()
+	check("きょうっつんなん", "")
+	c.Backspace()
+	c.Backspace()
+	check("きょうっつん", "")
+	c.TypeString("xts")
+	check("きょうっつん", "xts")
+	c.Backspace()
+	check("きょうっつん", "xt")
+	c.TypeString("u!")
+	check("きょうっつんっ!", "")
+
+	c.Reset()
+	check("", "")
+	c.Katakana = true
+	c.TypeString("ko-hi- ")
+	check("コーヒー ", "")
+	c.Katakana = false
+	c.TypeString("wonomu")
+	check("コーヒー をのむ", "")
+	c.TypeString("kon'nichiha")
+	check("コーヒー をのむこんにちは", "")
+	c.TypeString("Zoom")
+	check("コーヒー をのむこんにちはぞお", "m")
+	c.Commit()
+	check("コーヒー をのむこんにちはぞおm", "")
+}
+
+var scriptTests = []struct {
+	hira, kata string
+}{
+	{"ひらがな", "ヒラガナ"},
+	{"きょうっ", "キョウッ"},
+	{"ゔぁゕゖ", "ヴァヵヶ"},
+	{"ゝゞ", "ヽヾ"},
+	{"わ゙ゐ゙ゑ゙を゙", "ヷヸヹヺ"},
+	{"らーめん, 日本", "ラーメン, 日本"},
+}
+
+func TestScript(t *testing.T) {
+	for i, test := range scriptTests {
+		name := fmt.Sprintf("#%d: to katakana:", i)
+		kata := testPair{test.hira, test.kata}
+		testString(name, t, kata, ToKatakanaString)
+		testBytes(name, t, kata, ToKatakana)
+		testReader(name, t, kata, ToKatakanaReader)
+		name = fmt.Sprintf("#%d: to hiragana:", i)
+		hira := testPair{test.kata, test.hira}
+		testString(name, t, hira, ToHiraganaString)
+		testBytes(name, t, hira, ToHiragana)
+		testReader(name, t, hira, ToHiraganaReader)
+	}
+	// Katakana with no hiragana.
+	testString("to hiragana", t, testPair{"ㇰㇱ", "ㇰㇱ"}, ToHiraganaString)
+	testString("to katakana", t, testPair{"わ", "ワ"}, ToKatakanaString)
+}
+
+var halfWidthTests = []testPair{
+	{"ｶﾀｶﾅ", "カタカナ"},
+	{"ｶﾞｲﾄﾞﾌﾞｯｸ", "ガイドブック"},
+	{"ﾊﾟﾊﾞﾊ", "パバハ"},
+	{"ｳﾞｧｲｵﾘﾝ", "ヴァイオリン"},
+	{"ﾜﾞｦﾞ", "ヷヺ"},
+	{"ｺｰﾋｰ｡", "コーヒー。"},
+	{"ﾞｱﾟ", "゛ア゜"},
+	{"ｱｲｳ 123", "アイウ 123"},
+}
+
+func TestHalfWidth(t *testing.T) {
+	if len(halfWidth) != halfWidthLast-halfWidthFirst+1 {
+		t.Fatalf("%d half-width katakana; expected %d", len(halfWidth), halfWidthLast-halfWidthFirst+1)
+	}
+	for i, test := range halfWidthTests {
+		name := fmt.Sprintf("#%d: widen:", i)
+		testString(name, t, test, WidenKatakanaString)
+		testBytes(name, t, test, WidenKatakana)
+		testReader(name, t, test, WidenKatakanaReader)
+		// Romaji reads half-width katakana as it does the standard form.
+		roman := testPair{test.in, RomajiString(test.out)}
+		testString(name, t, roman, RomajiString)
+		testReader(name, t, roman, RomajiReader)
+	}
+	testString("half-width romaji", t, testPair{"ｶﾀｶﾅ", "katakana"}, RomajiString)
+	testString("half-width to hiragana", t, testPair{"ｶﾞｯｺｳ", "がっこう"}, ToHiraganaString)
+}
+
+var halfWidthKatakanaTests = []testPair{
+	{"gaidobukku", "ｶﾞｲﾄﾞﾌﾞｯｸ"},
+	{"ko-hi-", "ｺｰﾋｰ"},
+	{"pa", "ﾊﾟ"},
+	{"vaiorin", "ｳﾞｧｲｵﾘﾝ"},
+}
+
+func TestHalfWidthKatakana(t *testing.T) {
+	cfg := &KanaConfig{HalfWidth: true}
+	for i, test := range halfWidthKatakanaTests {
+		name := fmt.Sprintf("#%d: half-width katakana:", i)
+		testString(name, t, test, cfg.KatakanaString)
+		testBytes(name, t, test, cfg.Katakana)
+		testReader(name, t, test, cfg.KatakanaReader)
+	}
+	cfg = &KanaConfig{HalfWidth: true, MixedScript: true, Punctuation: IMEPunctuation}
+	testString("half-width mixed", t, testPair{"KOOHIIwonomu.", "ｺｵﾋｲをのむ。"}, cfg.HiraganaString)
+	testString("half-width mixed", t, testPair{"KO-HI-wonomu.", "ｺｰﾋｰをのむ。"}, cfg.HiraganaString)
+	testString("half-width mixed", t, testPair{"KO-HI-wonomu.", "ｺｰﾋｰをのむ｡"}, cfg.KatakanaString)
+	cfg = &KanaConfig{HalfWidth: true, Punctuation: IMEPunctuation}
+	testString("half-width hiragana", t, testPair{"sou. ko-hi-", "そう。 こーひー"}, cfg.HiraganaString)
+}
+
+var zenginTests = []struct {
+	in, out string
+	err     error
+}{
+	{"ヤマダ タロウ", "ﾔﾏﾀﾞ ﾀﾛｳ", nil},
+	{"ジェーシービー", "ｼﾞｴ-ｼ-ﾋﾞ-", nil},
+	{"ｷｯﾌﾟ", "ｷﾂﾌﾟ", nil},
+	{"カ)ニホン(ABC123)", "ｶ)ﾆﾎﾝ(ABC123)", nil},
+	{"ヴァヵヶヮ", "ｳﾞｱｶｹﾜ", nil},
+	{"やまだ・タロウ, abc", "やまだ･ﾀﾛｳ, abc", ZenginError("やまだ･abc")},
+}
+
+func TestZengin(t *testing.T) {
+	for i, test := range zenginTests {
+		out, err := Zengin(test.in)
+		if out != test.out || !reflect.DeepEqual(err, test.err) {
+			t.Errorf("#%d: %q: got %q %v; expected %q %v", i, test.in, out, err, test.out, test.err)
+		}
+	}
+}
+
+var widthTests = []struct {
+	narrow, wide string
+}{
+	{"Tokyo123", "Ｔｏｋｙｏ１２３"},
+	{"a b!~", "ａ　ｂ！～"},
+	{"東京 ひらがな", "東京　ひらがな"},
+	{"ガイド 1", "ｶﾞｲﾄﾞ　１"},
+}
+
+func TestWidth(t *testing.T) {
+	for i, test := range widthTests {
+		name := fmt.Sprintf("#%d: narrow:", i)
+		narrow := testPair{test.wide, test.narrow}
+		testString(name, t, narrow, NarrowString)
+		testBytes(name, t, narrow, Narrow)
+		testReader(name, t, narrow, NarrowReader)
+		name = fmt.Sprintf("#%d: widen:", i)
+		wide := testPair{test.narrow, strings.Replace(test.wide, "ｶﾞｲﾄﾞ", "ガイド", 1)}
+		testString(name, t, wide, WidenString)
+		testBytes(name, t, wide, Widen)
+		testReader(name, t, wide, WidenReader)
+	}
+	keep := &WidthConfig{KeepKatakana: true}
+	testString("keep katakana", t, testPair{"ｶﾀｶﾅ　ＡＢ", "ｶﾀｶﾅ AB"}, keep.NarrowString)
+	testString("keep katakana", t, testPair{"ｶﾀｶﾅ AB", "ｶﾀｶﾅ　ＡＢ"}, keep.WidenString)
+
+	cfg := &KanaConfig{FoldWidth: true}
+	testString("fold width", t, testPair{"Ｔｏｋｙｏ　ｄｅｓｕ", "ときょ です"}, cfg.HiraganaString)
+	testReader("fold width", t, testPair{"ＫＯＯＨＩＩ", "コオヒイ"}, cfg.KatakanaReader)
+	cfg = &KanaConfig{FoldWidth: true, EscapeOpen: '{', EscapeClose: '}'}
+	testString("fold width", t, testPair{"ｋａ{ｋａ}", "かｋａ"}, cfg.HiraganaString)
+	var diags []Diagnostic
+	cfg = &KanaConfig{FoldWidth: true, Diagnose: func(d Diagnostic) { diags = append(diags, d) }}
+	testString("fold width", t, testPair{"ｋａｑ ｋｉ", "かq き"}, cfg.HiraganaString)
+	if expect := []Diagnostic{{6, "q", StrayConsonant}}; !reflect.DeepEqual(diags, expect) {
+		t.Errorf("fold width: got %v; expected %v", diags, expect)
+	}
+}
+
+var iterationTests = []testPair{
+	{"いすゞ", "いすず"},
+	{"こゝろ", "こころ"},
+	{"ハヽ", "ハハ"},
+	{"ミスヾ", "ミスズ"},
+	{"じゝ", "じし"},
+	{"ぱゝ", "ぱは"},
+	{"うゞ", "うゔ"},
+	{"わゞ", "わわ"},
+	{"ワヾ", "ワヷ"},
+	{"こゝゝ", "こここ"},
+	{"ゝあ", "ゝあ"},
+	{"人々", "人々"},
+	{"日ゝ", "日ゝ"},
+}
+
+func TestIterationMarks(t *testing.T) {
+	for i, test := range iterationTests {
+		name := fmt.Sprintf("#%d: iteration marks:", i)
+		testString(name, t, test, ExpandIterationMarksString)
+		testBytes(name, t, test, ExpandIterationMarks)
+		testReader(name, t, test, ExpandIterationMarksReader)
+	}
+	for _, test := range []testPair{
+		{"いすゞ", "isuzu"},
+		{"こゝろ", "kokoro"},
+		{"ミスヾ", "misuzu"},
+		{"人々", "人々"},
+	} {
+		testString("romaji iteration marks", t, test, RomajiString)
+		testReader("romaji iteration marks", t, test, RomajiReader)
+	}
+}
+
+// TestComposerMatchesTranslator checks that typing romaji into a Composer
+// commits the kana that translating it all at once produces.
+func TestComposerMatchesTranslator(t *testing.T) {
+	inputs := []string{
+		"bkatta", "zka", "kitte", "matcha", "tchi", "kk", "qqa", "bkq",
+		"konnichiha", "kan'i", "kannna", "nk", "xtsu", "Tokyo", "ko-hi-", "ZZzka",
+	}
+	google, err := ReadTable(strings.NewReader(googleTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []*Table{nil, google} {
+		cfg := &KanaConfig{Table: table}
+		for _, katakana := range []bool{false, true} {
+			translate := cfg.HiraganaString
+			if katakana {
+				translate = cfg.KatakanaString
+			}
+			for _, in := range inputs {
+				c := Composer{Katakana: katakana, Table: table}
+				c.TypeString(in)
+				c.Commit()
+				if expect := translate(in); c.Committed() != expect {
+					t.Errorf("%q (katakana %t, table %t): composer %q; translator %q", in, katakana, table != nil, c.Committed(), expect)
+				}
+			}
+		}
+	}
+}
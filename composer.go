@@ -0,0 +1,132 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+import "unicode/utf8"
+
+// A Composer translates romaji into kana one keystroke at a time, as an
+// input method does. Romaji that may yet become something else as more is
+// typed, such as ky or a lone n, is held as the preedit; the rest is
+// committed as kana. The zero value is a Composer writing hiragana.
+type Composer struct {
+	// Katakana selects katakana rather than hiragana. It may be changed
+	// between keystrokes and applies to the kana committed afterwards.
+	Katakana bool
+	// Table, if not nil, replaces the built-in table of romaji.
+	Table *Table
+
+	committed []byte
+	preedit   []byte
+	// lastVowel is the vowel ending the katakana committed last, which
+	// a hyphen extends with ー.
+	lastVowel byte
+}
+
+// Type adds the keystroke r. ASCII letters, an apostrophe after them and
+// anything else that begins romaji in the table are romaji. Anything else
+// ends the romaji before it and is committed as it is, except that a hyphen
+// after katakana ending in a vowel is ー.
+func (c *Composer) Type(r rune) {
+	switch {
+	case r == '-' && c.lastVowel != 0 && len(c.preedit) == 0:
+		c.committed = append(c.committed, "ー"...)
+		c.lastVowel = 0
+	case r < utf8.RuneSelf && (isLetter(byte(r)) || r == '\'' && len(c.preedit) > 0 || c.script().table.begins(string(r))):
+		c.preedit = append(c.preedit, byte(r))
+		c.compose(false)
+	default:
+		c.compose(true)
+		c.committed = append(c.committed, string(r)...)
+		c.lastVowel = 0
+	}
+}
+
+// TypeString adds each rune of s as a keystroke.
+func (c *Composer) TypeString(s string) {
+	for _, r := range s {
+		c.Type(r)
+	}
+}
+
+// Backspace deletes the last letter of the preedit or, if the preedit
+// is empty, the last character committed.
+func (c *Composer) Backspace() {
+	if len(c.preedit) > 0 {
+		c.preedit = c.preedit[:len(c.preedit)-1]
+		return
+	}
+	_, w := utf8.DecodeLastRune(c.committed)
+	c.committed = c.committed[:len(c.committed)-w]
+	c.lastVowel = 0
+}
+
+// Commit translates the preedit as if no more romaji were to follow,
+// as at the end of the input, and commits it.
+func (c *Composer) Commit() {
+	c.compose(true)
+}
+
+// Reset discards the committed text and the preedit.
+func (c *Composer) Reset() {
+	c.committed = c.committed[:0]
+	c.preedit = c.preedit[:0]
+	c.lastVowel = 0
+}
+
+// Committed returns the text committed since the Composer was created or reset.
+func (c *Composer) Committed() string {
+	return string(c.committed)
+}
+
+// Preedit returns the romaji typed but not yet committed.
+func (c *Composer) Preedit() string {
+	return string(c.preedit)
+}
+
+// compose commits what it can of the preedit. Unless final is set, it
+// leaves romaji that more keystrokes could make part of a longer match.
+func (c *Composer) compose(final bool) {
+	sc := c.script()
+	for len(c.preedit) > 0 {
+		s := lowerString(string(c.preedit))
+		if !final && waits(sc, s) {
+			return
+		}
+		if n, kana, pending := sc.match(s); n > 0 {
+			c.committed = append(c.committed, kana...)
+			c.preedit = append([]byte(pending), c.preedit[n:]...)
+			c.lastVowel = 0
+			if v := s[n-1]; sc.katakana && vowelKana[v] != 0 {
+				c.lastVowel = v
+			}
+			continue
+		}
+		// The first letter begins no romaji. As in translateKana, whether
+		// it is a small tsu depends on whether romaji follows it.
+		rest := s[1:]
+		if !final && waits(sc, rest) {
+			return
+		}
+		if n, _, _ := sc.match(rest); n > 0 && doubles(s[0]) {
+			c.committed = append(c.committed, sc.sokuon...)
+		} else {
+			c.committed = append(c.committed, c.preedit[0])
+		}
+		c.preedit = c.preedit[1:]
+		c.lastVowel = 0
+	}
+}
+
+// script returns the script for the kana the Composer writes.
+func (c *Composer) script() *script {
+	return (&KanaConfig{Table: c.Table}).script(c.Katakana)
+}
+
+// waits reports whether more keystrokes could make the romaji s, which may
+// be empty, part of a longer match. A doubled n waits to see whether
+// a vowel follows.
+func waits(sc *script, s string) bool {
+	return s == "nn" || sc.table.hasPrefix(s)
+}
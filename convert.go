@@ -0,0 +1,144 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+import (
+	"bytes"
+	"io"
+)
+
+// converter implements conversion between hiragana and katakana.
+type converter struct {
+	t         *translator
+	katakana  bool // Convert to katakana rather than hiragana.
+	widenOnly bool // Only widen half-width katakana.
+}
+
+// ToKatakana rewrites the hiragana in text as katakana and returns the result.
+// The hiragana iteration marks ゝ and ゞ become ヽ and ヾ, and わ, ゐ, ゑ and を
+// followed by a combining voiced sound mark become ヷ, ヸ, ヹ and ヺ.
+// Half-width katakana is widened first, as by WidenKatakana.
+// Everything else is unchanged.
+func ToKatakana(text []byte) []byte {
+	var buf bytes.Buffer
+	c := converter{t: newTranslator(bytesGetter(text), bufPutter(&buf), nil), katakana: true}
+	c.translate()
+	return buf.Bytes()
+}
+
+// ToKatakanaString rewrites the hiragana in text as katakana and returns the result.
+// It converts as ToKatakana does.
+func ToKatakanaString(text string) string {
+	var buf bytes.Buffer
+	c := converter{t: newTranslator(stringGetter(text), bufPutter(&buf), nil), katakana: true}
+	c.translate()
+	return buf.String()
+}
+
+// ToKatakanaReader returns an io.Reader that will rewrite the hiragana in its
+// input as katakana. It converts as ToKatakana does.
+func ToKatakanaReader(rd io.Reader) io.Reader {
+	ch := make(chan byte, 100)
+	c := &converter{t: newTranslator(readerGetter(rd), chanPutter(ch), ch), katakana: true}
+	go c.translate()
+	return c
+}
+
+// ToHiragana rewrites the katakana in text as hiragana and returns the result.
+// The katakana iteration marks ヽ and ヾ become ゝ and ゞ, and ヷ, ヸ, ヹ and ヺ,
+// which have no hiragana, become わ, ゐ, ゑ and を followed by a combining voiced
+// sound mark. Half-width katakana is widened first, as by WidenKatakana.
+// Everything else, including the prolonged sound mark ー, which
+// is used with both scripts, and the small katakana ㇰ to ㇿ, is unchanged.
+func ToHiragana(text []byte) []byte {
+	var buf bytes.Buffer
+	c := converter{t: newTranslator(bytesGetter(text), bufPutter(&buf), nil)}
+	c.translate()
+	return buf.Bytes()
+}
+
+// ToHiraganaString rewrites the katakana in text as hiragana and returns the result.
+// It converts as ToHiragana does.
+func ToHiraganaString(text string) string {
+	var buf bytes.Buffer
+	c := converter{t: newTranslator(stringGetter(text), bufPutter(&buf), nil)}
+	c.translate()
+	return buf.String()
+}
+
+// ToHiraganaReader returns an io.Reader that will rewrite the katakana in its
+// input as hiragana. It converts as ToHiragana does.
+func ToHiraganaReader(rd io.Reader) io.Reader {
+	ch := make(chan byte, 100)
+	c := &converter{t: newTranslator(readerGetter(rd), chanPutter(ch), ch)}
+	go c.translate()
+	return c
+}
+
+func (c *converter) Read(p []byte) (int, error) {
+	return c.t.Read(p)
+}
+
+// voicedSoundMark is the combining form of ゛.
+const voicedSoundMark = '\u3099'
+
+// voicedW maps the katakana with no hiragana to the hiragana that,
+// with a voiced sound mark, spells them.
+var voicedW = map[rune]rune{
+	'ヷ': 'わ',
+	'ヸ': 'ゐ',
+	'ヹ': 'ゑ',
+	'ヺ': 'を',
+}
+
+// unvoicedW is the inverse of voicedW.
+var unvoicedW = map[rune]rune{
+	'わ': 'ヷ',
+	'ゐ': 'ヸ',
+	'ゑ': 'ヹ',
+	'を': 'ヺ',
+}
+
+// iterationMarks maps each iteration mark to its twin in the other script.
+var iterationMarks = map[rune]rune{
+	'ゝ': 'ヽ',
+	'ゞ': 'ヾ',
+	'ヽ': 'ゝ',
+	'ヾ': 'ゞ',
+}
+
+func (c *converter) translate() {
+	t := c.t
+	t.get = halfWidthGetter(t.get)
+	for {
+		r := t.next()
+		if r == eof {
+			break
+		}
+		switch {
+		case c.widenOnly:
+			t.putRune(r)
+		case iterationMarks[r] != 0:
+			if c.katakana == (r == 'ゝ' || r == 'ゞ') {
+				r = iterationMarks[r]
+			}
+			t.putRune(r)
+		case c.katakana:
+			if kata, ok := unvoicedW[r]; ok && t.peek() == voicedSoundMark {
+				t.next()
+				r = kata
+			}
+			t.putRune(toKatakana(r))
+		case voicedW[r] != 0:
+			t.putRune(voicedW[r])
+			t.putRune(voicedSoundMark)
+		default:
+			t.putRune(toHiragana(r))
+		}
+	}
+	if t.ch != nil {
+		close(t.ch)
+	}
+}
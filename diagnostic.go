@@ -0,0 +1,77 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+import "fmt"
+
+// A Diagnostic describes a fragment of romaji that was not converted to kana.
+type Diagnostic struct {
+	Offset int    // Byte offset of the fragment in the input.
+	Text   string // The fragment.
+	Reason Reason // Why it was not converted.
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("offset %d: %s %q", d.Offset, d.Reason, d.Text)
+}
+
+// A Reason explains why romaji was not converted to kana.
+type Reason int
+
+const (
+	// StrayConsonant is a single letter that begins no syllable, such as q in "q ka".
+	StrayConsonant Reason = iota
+	// UnknownSyllable is a run of letters that spells no syllable, such as kyx.
+	// The run is reported whole, not split into syllables and stray letters.
+	UnknownSyllable
+	// DanglingSmallKana is a small kana, such as xya, with no kana before it
+	// to modify. It is converted nonetheless.
+	DanglingSmallKana
+)
+
+func (r Reason) String() string {
+	switch r {
+	case StrayConsonant:
+		return "stray consonant"
+	case UnknownSyllable:
+		return "unknown syllable"
+	case DanglingSmallKana:
+		return "dangling small kana"
+	}
+	return "Reason(?)"
+}
+
+// HiraganaDiagnostics translates romaji into hiragana as configured by c and
+// returns the result and a Diagnostic for each fragment that was not converted.
+func (c *KanaConfig) HiraganaDiagnostics(romaji string) (string, []Diagnostic) {
+	d := c.collect()
+	return d.cfg.HiraganaString(romaji), d.diags
+}
+
+// KatakanaDiagnostics translates romaji into katakana as configured by c and
+// returns the result and a Diagnostic for each fragment that was not converted.
+func (c *KanaConfig) KatakanaDiagnostics(romaji string) (string, []Diagnostic) {
+	d := c.collect()
+	return d.cfg.KatakanaString(romaji), d.diags
+}
+
+// diagnostics collects the Diagnostics reported by a translation using cfg.
+type diagnostics struct {
+	cfg   KanaConfig
+	diags []Diagnostic
+}
+
+// collect returns a copy of c that collects Diagnostics, as well as passing
+// them to c.Diagnose.
+func (c *KanaConfig) collect() *diagnostics {
+	d := &diagnostics{cfg: *c}
+	d.cfg.Diagnose = func(diag Diagnostic) {
+		d.diags = append(d.diags, diag)
+		if c.Diagnose != nil {
+			c.Diagnose(diag)
+		}
+	}
+	return d
+}
@@ -11,36 +11,56 @@ import (
 
 // hiragana implements transliteration of romaji to hiragana.
 type hiragana struct {
-	t *translator
+	t   *translator
+	cfg *KanaConfig
 }
 
 // Hiragana translates romaji into hiragana and returns the result.
 func Hiragana(romaji []byte) []byte {
+	return new(KanaConfig).Hiragana(romaji)
+}
+
+// HiraganaString translates romaji into hiragana and returns the result.
+func HiraganaString(romaji string) string {
+	return new(KanaConfig).HiraganaString(romaji)
+}
+
+// HiraganaReader returns an io.Reader that will translate romaji in its input into hiragana.
+func HiraganaReader(rd io.Reader) io.Reader {
+	return new(KanaConfig).HiraganaReader(rd)
+}
+
+// Hiragana translates romaji into hiragana as configured by c and returns the result.
+func (c *KanaConfig) Hiragana(romaji []byte) []byte {
 	var buf bytes.Buffer
-	r := hiragana{
-		t: newTranslator(bytesGetter(romaji), bufPutter(&buf), nil),
+	h := hiragana{
+		t:   newTranslator(bytesGetter(romaji), bufPutter(&buf), nil),
+		cfg: c,
 	}
-	translateHiragana(r.t)
+	h.translate()
 	return buf.Bytes()
 }
 
-// HiraganaString translates romaji into hiragana and returns the result.
-func HiraganaString(romaji string) string {
+// HiraganaString translates romaji into hiragana as configured by c and returns the result.
+func (c *KanaConfig) HiraganaString(romaji string) string {
 	var buf bytes.Buffer
 	h := hiragana{
-		t: newTranslator(stringGetter(romaji), bufPutter(&buf), nil),
+		t:   newTranslator(stringGetter(romaji), bufPutter(&buf), nil),
+		cfg: c,
 	}
-	translateHiragana(h.t)
+	h.translate()
 	return buf.String()
 }
 
-// HiraganaReader returns an io.Reader that will translate romaji in its input into hiragana.
-func HiraganaReader(rd io.Reader) io.Reader {
+// HiraganaReader returns an io.Reader that will translate romaji in its input
+// into hiragana as configured by c.
+func (c *KanaConfig) HiraganaReader(rd io.Reader) io.Reader {
 	ch := make(chan byte, 100)
 	h := &hiragana{
-		t: newTranslator(readerGetter(rd), chanPutter(ch), ch),
+		t:   newTranslator(readerGetter(rd), chanPutter(ch), ch),
+		cfg: c,
 	}
-	go translateHiragana(h.t)
+	go h.translate()
 	return h
 }
 
@@ -48,59 +68,8 @@ func (h *hiragana) Read(p []byte) (int, error) {
 	return h.t.Read(p)
 }
 
-func translateHiragana(t *translator) {
-	prevByte := -1
-	mark := func() {
-		if isConsonant[prevByte] {
-			t.putString("っ")
-		} else if prevByte >= 0 {
-			t.put(byte(prevByte))
-		}
-		prevByte = -1
-	}
-	for {
-		s := t.next3()
-		if len(s) == 0 {
-			break
-		}
-		if len(s) == 3 {
-			cha, ok := threeH[s]
-			if ok {
-				mark()
-				prevByte = -1
-				t.putString(cha)
-				t.advance(3)
-				continue
-			}
-		}
-		if len(s) >= 2 {
-			ka, ok := twoH[s[:2]]
-			if ok {
-				mark()
-				t.putString(ka)
-				t.advance(2)
-				continue
-			}
-		}
-		a, ok := oneH[s[:1]]
-		if ok {
-			mark()
-			t.putString(a)
-			t.advance(1)
-			continue
-		}
-		if prevByte >= 0 {
-			t.put(byte(prevByte))
-		}
-		prevByte = int(s[0])
-		t.advance(1)
-	}
-	if prevByte >= 0 {
-		t.put(byte(prevByte))
-	}
-	if t.ch != nil {
-		close(t.ch)
-	}
+func (h *hiragana) translate() {
+	translateKana(h.t, h.cfg, false)
 }
 
 // Note the absence of n and m.
@@ -126,201 +95,310 @@ var isConsonant = map[int]bool{
 	'z': true,
 }
 
-var oneH = map[string]string{
-	"a": "あ",
-	"i": "い",
-	"u": "う",
-	"e": "え",
-	"o": "お",
-	"n": "ん",
-}
-
-var twoH = map[string]string{
-	"xa": "ぁ",
-	"xi": "ぃ",
-	"xu": "ぅ",
-	"xe": "ぇ",
-	"xo": "ぉ",
-	"la": "ぁ",
-	"li": "ぃ",
-	"lu": "ぅ",
-	"le": "ぇ",
-	"lo": "ぉ",
-
-	"ka": "か",
-	"ki": "き",
-	"ku": "く",
-	"ke": "け",
-	"ko": "こ",
-
-	"ga": "が",
-	"gi": "ぎ",
-	"gu": "ぐ",
-	"ge": "げ",
-	"go": "ご",
-
-	"sa": "さ",
-	"si": "し",
-	"su": "す",
-	"se": "せ",
-	"so": "そ",
-
-	"za": "ざ",
-	"ji": "じ",
-	"zi": "じ",
-	"zu": "ず",
-	"ze": "ぜ",
-	"zo": "ぞ",
-
-	"ja": "じゃ",
-	"ju": "じゅ",
-	"jo": "じょ",
-
-	"ta": "た",
-	"te": "て",
-	"to": "と",
-
-	"ti": "ち",
-	"tu": "つ",
-
-	"di": "ぢ",
-	"du": "づ",
-
-	"da": "だ",
-	"de": "で",
-	"do": "ど",
-
-	"na": "な",
-	"ni": "に",
-	"nu": "ぬ",
-	"ne": "ね",
-	"no": "の",
-
-	"ha": "は",
-	"hi": "ひ",
-	"fu": "ふ",
-	"hu": "ふ",
-	"he": "へ",
-	"ho": "ほ",
-
-	"ba": "ば",
-	"bi": "び",
-	"bu": "ぶ",
-	"be": "べ",
-	"bo": "ぼ",
-
-	"pa": "ぱ",
-	"pi": "ぴ",
-	"pu": "ぷ",
-	"pe": "ぺ",
-	"po": "ぽ",
-
-	"fa": "ふぁ",
-	"fi": "ふぃ",
-	"fe": "ふぇ",
-	"fo": "ふぉ",
-
-	"ma": "ま",
-	"mi": "み",
-	"mu": "む",
-	"me": "め",
-	"mo": "も",
-
-	"ya": "や",
-	"yu": "ゆ",
+// romajiH holds the romaji for hiragana, with the same spellings as the
+// romaji tables of the Microsoft and Google input methods.
+var romajiH = map[string]string{
+	"a":  "あ",
+	"i":  "い",
+	"u":  "う",
+	"e":  "え",
+	"o":  "お",
+	"yi": "い",
 	"ye": "いぇ",
-	"yo": "よ",
+	"wu": "う",
+
+	"xa":  "ぁ",
+	"xi":  "ぃ",
+	"xu":  "ぅ",
+	"xe":  "ぇ",
+	"xo":  "ぉ",
+	"la":  "ぁ",
+	"li":  "ぃ",
+	"lu":  "ぅ",
+	"le":  "ぇ",
+	"lo":  "ぉ",
+	"xyi": "ぃ",
+	"xye": "ぇ",
+	"lyi": "ぃ",
+	"lye": "ぇ",
 
-	"ra": "ら",
-	"ri": "り",
-	"ru": "る",
-	"re": "れ",
-	"ro": "ろ",
-
-	"wa": "わ",
-	//"wi": "ゐ",
-	//"we": "ゑ",
-	"wo": "を",
-
-	//"wa": "うぁ",
-	"wi": "うぃ",
-	"we": "うぇ",
-	//"wo": "うぉ",
-
-	"va": "ゔぁ",
-	"vi": "ゔぃ",
-	"vu": "ゔ",
-	"ve": "ゔぇ",
-	"vo": "ゔぉ",
-}
+	"xya": "ゃ",
+	"xyu": "ゅ",
+	"xyo": "ょ",
+	"lya": "ゃ",
+	"lyu": "ゅ",
+	"lyo": "ょ",
 
-var threeH = map[string]string{
+	"xtu":  "っ",
+	"ltu":  "っ",
+	"xtsu": "っ",
+	"ltsu": "っ",
+	"xwa":  "ゎ",
+	"lwa":  "ゎ",
+	"xka":  "ゕ",
+	"lka":  "ゕ",
+	"xke":  "ゖ",
+	"lke":  "ゖ",
+	"xn":   "ん",
+
+	"ka":  "か",
+	"ki":  "き",
+	"ku":  "く",
+	"ke":  "け",
+	"ko":  "こ",
+	"ca":  "か",
+	"ci":  "し",
+	"cu":  "く",
+	"ce":  "せ",
+	"co":  "こ",
 	"kya": "きゃ",
+	"kyi": "きぃ",
 	"kyu": "きゅ",
+	"kye": "きぇ",
 	"kyo": "きょ",
 
+	"kwa": "くぁ",
+	"kwi": "くぃ",
+	"kwu": "くぅ",
+	"kwe": "くぇ",
+	"kwo": "くぉ",
+	"qa":  "くぁ",
+	"qi":  "くぃ",
+	"qu":  "く",
+	"qe":  "くぇ",
+	"qo":  "くぉ",
+	"qwa": "くぁ",
+	"qwi": "くぃ",
+	"qwu": "くぅ",
+	"qwe": "くぇ",
+	"qwo": "くぉ",
+	"qya": "くゃ",
+	"qyu": "くゅ",
+	"qyo": "くょ",
+
+	"ga":  "が",
+	"gi":  "ぎ",
+	"gu":  "ぐ",
+	"ge":  "げ",
+	"go":  "ご",
 	"gya": "ぎゃ",
+	"gyi": "ぎぃ",
 	"gyu": "ぎゅ",
+	"gye": "ぎぇ",
 	"gyo": "ぎょ",
-
+	"gwa": "ぐぁ",
+	"gwi": "ぐぃ",
+	"gwu": "ぐぅ",
+	"gwe": "ぐぇ",
+	"gwo": "ぐぉ",
+
+	"sa":  "さ",
+	"si":  "し",
+	"su":  "す",
+	"se":  "せ",
+	"so":  "そ",
 	"shi": "し",
-
 	"sha": "しゃ",
 	"shu": "しゅ",
+	"she": "しぇ",
 	"sho": "しょ",
 	"sya": "しゃ",
+	"syi": "しぃ",
 	"syu": "しゅ",
+	"sye": "しぇ",
 	"syo": "しょ",
-
+	"swa": "すぁ",
+	"swi": "すぃ",
+	"swu": "すぅ",
+	"swe": "すぇ",
+	"swo": "すぉ",
+
+	"za":  "ざ",
+	"zi":  "じ",
+	"zu":  "ず",
+	"ze":  "ぜ",
+	"zo":  "ぞ",
+	"ji":  "じ",
+	"ja":  "じゃ",
+	"ju":  "じゅ",
+	"je":  "じぇ",
+	"jo":  "じょ",
+	"jya": "じゃ",
+	"jyi": "じぃ",
+	"jyu": "じゅ",
+	"jye": "じぇ",
+	"jyo": "じょ",
+	"zya": "じゃ",
+	"zyi": "じぃ",
+	"zyu": "じゅ",
+	"zye": "じぇ",
+	"zyo": "じょ",
+
+	"ta":  "た",
+	"ti":  "ち",
+	"tu":  "つ",
+	"te":  "て",
+	"to":  "と",
 	"chi": "ち",
 	"tsu": "つ",
-
-	"dhi": "でぃ",
-	"dhu": "でゅ",
-	"dwu": "どぅ",
-
 	"cha": "ちゃ",
-	"tya": "ちゃ",
 	"chu": "ちゅ",
-	"tyu": "ちゅ",
 	"che": "ちぇ",
-	"tye": "ちぇ",
 	"cho": "ちょ",
+	"cya": "ちゃ",
+	"cyi": "ちぃ",
+	"cyu": "ちゅ",
+	"cye": "ちぇ",
+	"cyo": "ちょ",
+	"tya": "ちゃ",
+	"tyi": "ちぃ",
+	"tyu": "ちゅ",
+	"tye": "ちぇ",
 	"tyo": "ちょ",
 
-	"dha": "ぢゃ",
-	//"dhu": "ぢゅ",
-	"dhe": "ぢぇ",
-	"dho": "ぢょ",
-
+	"tsa": "つぁ",
+	"tsi": "つぃ",
+	"tse": "つぇ",
+	"tso": "つぉ",
+	"tha": "てゃ",
+	"thi": "てぃ",
+	"thu": "てゅ",
+	"the": "てぇ",
+	"tho": "てょ",
+	"twa": "とぁ",
+	"twi": "とぃ",
+	"twu": "とぅ",
+	"twe": "とぇ",
+	"two": "とぉ",
+
+	"da":  "だ",
+	"di":  "ぢ",
+	"du":  "づ",
+	"de":  "で",
+	"do":  "ど",
+	"dya": "ぢゃ",
+	"dyi": "ぢぃ",
+	"dyu": "ぢゅ",
+	"dye": "ぢぇ",
+	"dyo": "ぢょ",
+	"dha": "でゃ",
+	"dhi": "でぃ",
+	"dhu": "でゅ",
+	"dhe": "でぇ",
+	"dho": "でょ",
+	"dwa": "どぁ",
+	"dwi": "どぃ",
+	"dwu": "どぅ",
+	"dwe": "どぇ",
+	"dwo": "どぉ",
+
+	"na":  "な",
+	"ni":  "に",
+	"nu":  "ぬ",
+	"ne":  "ね",
+	"no":  "の",
+	"n":   "ん",
 	"nya": "にゃ",
+	"nyi": "にぃ",
 	"nyu": "にゅ",
+	"nye": "にぇ",
 	"nyo": "にょ",
 
+	"ha":  "は",
+	"hi":  "ひ",
+	"hu":  "ふ",
+	"he":  "へ",
+	"ho":  "ほ",
+	"fu":  "ふ",
 	"hya": "ひゃ",
+	"hyi": "ひぃ",
 	"hyu": "ひゅ",
+	"hye": "ひぇ",
 	"hyo": "ひょ",
 
+	"fa":  "ふぁ",
+	"fi":  "ふぃ",
+	"fe":  "ふぇ",
+	"fo":  "ふぉ",
+	"fwa": "ふぁ",
+	"fwi": "ふぃ",
+	"fwu": "ふぅ",
+	"fwe": "ふぇ",
+	"fwo": "ふぉ",
+	"fya": "ふゃ",
+	"fyi": "ふぃ",
+	"fyu": "ふゅ",
+	"fye": "ふぇ",
+	"fyo": "ふょ",
+
+	"ba":  "ば",
+	"bi":  "び",
+	"bu":  "ぶ",
+	"be":  "べ",
+	"bo":  "ぼ",
 	"bya": "びゃ",
+	"byi": "びぃ",
 	"byu": "びゅ",
+	"bye": "びぇ",
 	"byo": "びょ",
 
+	"pa":  "ぱ",
+	"pi":  "ぴ",
+	"pu":  "ぷ",
+	"pe":  "ぺ",
+	"po":  "ぽ",
 	"pya": "ぴゃ",
+	"pyi": "ぴぃ",
 	"pyu": "ぴゅ",
+	"pye": "ぴぇ",
 	"pyo": "ぴょ",
 
+	"ma":  "ま",
+	"mi":  "み",
+	"mu":  "む",
+	"me":  "め",
+	"mo":  "も",
 	"mya": "みゃ",
+	"myi": "みぃ",
 	"myu": "みゅ",
+	"mye": "みぇ",
 	"myo": "みょ",
 
-	"xya": "ゃ",
-	"xyu": "ゅ",
-	"xyo": "ょ",
-	"lya": "ゃ",
-	"lyu": "ゅ",
-	"lyo": "ょ",
+	"ya": "や",
+	"yu": "ゆ",
+	"yo": "よ",
 
+	"ra":  "ら",
+	"ri":  "り",
+	"ru":  "る",
+	"re":  "れ",
+	"ro":  "ろ",
 	"rya": "りゃ",
+	"ryi": "りぃ",
 	"ryu": "りゅ",
+	"rye": "りぇ",
 	"ryo": "りょ",
+
+	"wa":  "わ",
+	"wi":  "うぃ",
+	"we":  "うぇ",
+	"wo":  "を",
+	"wha": "うぁ",
+	"whi": "うぃ",
+	"whu": "う",
+	"whe": "うぇ",
+	"who": "うぉ",
+	"wyi": "ゐ",
+	"wye": "ゑ",
+
+	"va":  "ゔぁ",
+	"vi":  "ゔぃ",
+	"vu":  "ゔ",
+	"ve":  "ゔぇ",
+	"vo":  "ゔぉ",
+	"vya": "ゔゃ",
+	"vyi": "ゔぃ",
+	"vyu": "ゔゅ",
+	"vye": "ゔぇ",
+	"vyo": "ゔょ",
 }
@@ -0,0 +1,119 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+import (
+	"bytes"
+	"io"
+)
+
+// expander implements the expansion of iteration marks.
+type expander struct {
+	t *translator
+}
+
+// ExpandIterationMarks rewrites the kana iteration marks ゝ, ゞ, ヽ and ヾ in
+// text as the kana they repeat and returns the result, so いすゞ is いすず and
+// こゝろ is こころ. The marks with a dakuten repeat the kana voiced, the others
+// unvoiced. A mark that does not follow a kana, and the kanji iteration
+// mark 々, are unchanged.
+func ExpandIterationMarks(text []byte) []byte {
+	var buf bytes.Buffer
+	e := expander{newTranslator(bytesGetter(text), bufPutter(&buf), nil)}
+	e.translate()
+	return buf.Bytes()
+}
+
+// ExpandIterationMarksString rewrites the kana iteration marks in text as the
+// kana they repeat and returns the result. It expands as ExpandIterationMarks does.
+func ExpandIterationMarksString(text string) string {
+	var buf bytes.Buffer
+	e := expander{newTranslator(stringGetter(text), bufPutter(&buf), nil)}
+	e.translate()
+	return buf.String()
+}
+
+// ExpandIterationMarksReader returns an io.Reader that will rewrite the kana
+// iteration marks in its input as the kana they repeat. It expands as
+// ExpandIterationMarks does.
+func ExpandIterationMarksReader(rd io.Reader) io.Reader {
+	ch := make(chan byte, 100)
+	e := &expander{newTranslator(readerGetter(rd), chanPutter(ch), ch)}
+	go e.translate()
+	return e
+}
+
+func (e *expander) Read(p []byte) (int, error) {
+	return e.t.Read(p)
+}
+
+func (e *expander) translate() {
+	t := e.t
+	t.get = iterationGetter(t.get)
+	for {
+		r := t.next()
+		if r == eof {
+			break
+		}
+		t.putRune(r)
+	}
+	if t.ch != nil {
+		close(t.ch)
+	}
+}
+
+// iterationGetter returns a getter that reads from get, replacing each
+// kana iteration mark that follows a kana with the kana it repeats.
+func iterationGetter(get func() rune) func() rune {
+	prev := rune(eof) // The kana most recently returned.
+	return func() rune {
+		c := get()
+		switch {
+		case prev == eof:
+		case c == 'ゝ', c == 'ヽ':
+			c = unvoiced(prev)
+		case c == 'ゞ', c == 'ヾ':
+			c = voiced(prev)
+		}
+		prev = eof
+		if 'ぁ' <= c && c <= 'ゖ' || 'ァ' <= c && c <= 'ヺ' {
+			prev = c
+		}
+		return c
+	}
+}
+
+// voiced returns the kana r voiced, or r itself if it has no voiced form.
+func voiced(r rune) rune {
+	v, ok := voice(toKatakana(r), 'ﾞ')
+	switch {
+	case !ok:
+		return r
+	case r != toKatakana(r):
+		// Hiragana. There is no hiragana for ヷ and ヺ.
+		if h := toHiragana(v); h != v {
+			return h
+		}
+		return r
+	}
+	return v
+}
+
+// unvoiced returns the kana r without its voiced or semi-voiced sound mark,
+// or r itself if it has none.
+func unvoiced(r rune) rune {
+	k := toKatakana(r)
+	for _, base := range []rune{k - 1, k - 2, k - ('ヷ' - 'ワ'), 'ウ'} {
+		for _, mark := range "ﾞﾟ" {
+			if v, ok := voice(base, mark); ok && v == k {
+				if r != k {
+					return toHiragana(base)
+				}
+				return base
+			}
+		}
+	}
+	return r
+}
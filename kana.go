@@ -0,0 +1,505 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A KanaConfig controls the translation of romaji into hiragana and katakana.
+// The zero value is the configuration used by Hiragana, Katakana and their
+// String and Reader variants.
+//
+// Romaji is matched without regard to case. A vowel marked long with a
+// macron or circumflex, precomposed or combining, is a long vowel: ō is おう
+// in hiragana and オー in katakana.
+type KanaConfig struct {
+	// LongVowels writes a doubled vowel in katakana as the vowel followed
+	// by the prolonged sound mark ー, so koohii is コーヒー.
+	LongVowels bool
+	// MixedScript writes romaji in upper case as katakana and other romaji
+	// as hiragana, whichever script is requested: KOOHIIwonomu is コオヒイをのむ.
+	MixedScript bool
+	// DoubleO writes a long o marked with a macron or circumflex as おお
+	// rather than おう in hiragana. Katakana writes all such vowels with ー.
+	DoubleO bool
+	// EscapeOpen and EscapeClose, if not zero, delimit text that is to be
+	// copied without change, such as {Zoom} or `Zoom`. The delimiters
+	// themselves are removed. An unclosed escape runs to the end of the input.
+	EscapeOpen, EscapeClose rune
+	// Strict converts only words that are entirely romaji, copying others
+	// unchanged, so that good morning is left alone. A word is a run of
+	// ASCII letters, including an apostrophe after n and hyphens. It is
+	// romaji if it is a sequence of romaji from the tables, each perhaps
+	// preceded by a doubled consonant (kk, or tc for っち), with hyphens
+	// following vowels only when they will be written as ー.
+	Strict bool
+	// Diagnose, if not nil, is called for each fragment of romaji that is
+	// not converted, in the order they appear in the input. The Reader
+	// variants call it from the goroutine doing the translation. Words
+	// that Strict leaves alone are not reported.
+	Diagnose func(Diagnostic)
+	// Punctuation, if not nil, maps ASCII punctuation to the Japanese
+	// punctuation that replaces it. IMEPunctuation is the usual mapping.
+	Punctuation map[rune]string
+	// DropSpaces drops spaces following kana or Japanese punctuation,
+	// so that sou desu ka is そうですか.
+	DropSpaces bool
+	// Widen writes ASCII that is not converted in full width, and
+	// a space as an ideographic space.
+	Widen bool
+	// HalfWidth writes katakana in half width, with voiced kana written as
+	// the kana and a separate sound mark: ガ is ｶﾞ. When translating into
+	// katakana, the Japanese punctuation that has a half-width form is
+	// written in half width too. Hiragana, and the punctuation written
+	// when translating into hiragana, are unaffected.
+	HalfWidth bool
+	// FoldWidth reads full-width ASCII and the ideographic space in the
+	// input, outside escapes, as ASCII, so Ｔｏｋｙｏ is matched as Tokyo.
+	FoldWidth bool
+	// Table, if not nil, replaces the built-in table of romaji. The
+	// syllabic n, doubled consonants and hyphens after katakana are
+	// handled as usual whatever the table holds.
+	Table *Table
+}
+
+// script holds what is needed to translate romaji into hiragana or katakana.
+type script struct {
+	table    *Table
+	maxLen   int    // The most input that match needs to see.
+	sokuon   string // The small tsu that doubles a consonant.
+	n        string // The syllabic n.
+	katakana bool
+}
+
+// script returns the script for translating romaji into katakana or
+// hiragana with the table configured by c.
+func (c *KanaConfig) script(katakana bool) *script {
+	table := c.Table
+	if table == nil {
+		table = defaultTable
+	}
+	sc := &script{table: table, maxLen: table.maxLen, sokuon: "っ", n: "ん"}
+	if katakana {
+		sc.sokuon, sc.n, sc.katakana = "ッ", "ン", true
+	}
+	// Enough to see the letter after nn.
+	if sc.maxLen < 3 {
+		sc.maxLen = 3
+	}
+	return sc
+}
+
+// match returns the length of the longest romaji at the start of s, which
+// must be lower case, the kana it spells and the input, if any, that
+// replaces it once it is matched. The length is 0 if there is no match.
+func (sc *script) match(s string) (int, string, string) {
+	if n := syllabicN(s); n > 0 {
+		return n, sc.n, ""
+	}
+	n, e := sc.table.match(s)
+	if n == 0 {
+		return 0, "", ""
+	}
+	if sc.katakana {
+		return n, katakanaString(e.kana), e.pending
+	}
+	return n, e.kana, e.pending
+}
+
+// translateKana translates romaji into the script sc as configured by cfg.
+func translateKana(t *translator, cfg *KanaConfig, katakana bool) {
+	sc := cfg.script(katakana)
+	// The scripts for MixedScript.
+	hira, kata := cfg.script(false), cfg.script(true)
+	t.get = cfg.longVowelGetter(t, sc)
+	if cfg.FoldWidth {
+		t.get = cfg.widthGetter(t, t.get)
+	}
+	// frag holds the letters, starting at offset fragOff, that pass has
+	// most recently written without converting them.
+	var frag []byte
+	fragOff := 0
+	// diagnose reports frag. It is called whenever anything else is written,
+	// so that the diagnostics come in the order they appear in the input.
+	diagnose := func() {
+		if len(frag) == 0 {
+			return
+		}
+		reason := UnknownSyllable
+		if len(frag) == 1 {
+			reason = StrayConsonant
+		}
+		cfg.Diagnose(Diagnostic{t.inputOffset(fragOff), string(frag), reason})
+		frag = frag[:0]
+	}
+	// pass writes the byte at offset off, which is not converted.
+	pass := func(b byte, off int) {
+		if !isLetter(b) {
+			diagnose()
+		} else if cfg.Diagnose != nil {
+			if len(frag) > 0 && fragOff+len(frag) != off {
+				diagnose()
+			}
+			if len(frag) == 0 {
+				fragOff = off
+			}
+			frag = append(frag, b)
+		}
+		if cfg.Widen && b < utf8.RuneSelf {
+			t.putRune(widen(rune(b)))
+		} else {
+			t.put(b)
+		}
+	}
+	// putKana writes kana or Japanese punctuation in the script sc.
+	putKana := func(s string, sc *script) {
+		diagnose()
+		if cfg.HalfWidth && sc.katakana {
+			s = narrowString(s)
+		}
+		t.putString(s)
+	}
+	prevByte, prevOff := -1, 0
+	flush := func() {
+		if prevByte >= 0 {
+			pass(byte(prevByte), prevOff)
+		}
+		prevByte = -1
+	}
+	mark := func(sc *script) {
+		if prevByte >= 0 && doubles(byte(prevByte)) {
+			putKana(sc.sokuon, sc)
+			prevByte = -1
+		}
+		flush()
+	}
+	// lastKana records whether kana or Japanese punctuation was written last.
+	lastKana := false
+	// lastVowel is the vowel ending the most recent katakana, which a hyphen
+	// or, if so configured, a repetition of the vowel extends with ー.
+	lastVowel := byte(0)
+	// tokenEnd is the offset of the end of the word being converted in strict mode.
+	tokenEnd := 0
+	for {
+		raw := t.lookahead(sc.maxLen)
+		if len(raw) == 0 {
+			break
+		}
+		if open := string(cfg.EscapeOpen); cfg.EscapeOpen != 0 && t.lookahead(len(open)) == open {
+			flush()
+			diagnose()
+			t.advance(len(open))
+			cfg.escape(t)
+			lastKana = false
+			lastVowel = 0
+			continue
+		}
+		if cfg.Strict && t.offset >= tokenEnd && isLetter(raw[0]) {
+			tok := word(t)
+			if !cfg.parses(sc, tok) {
+				flush()
+				diagnose()
+				t.putString(tok)
+				t.advance(len(tok))
+				lastKana = false
+				lastVowel = 0
+				continue
+			}
+			tokenEnd = t.offset + len(tok)
+		}
+		s := lowerString(raw)
+		if lastVowel != 0 && (s[0] == '-' || cfg.LongVowels && s[0] == lastVowel) {
+			putKana("ー", kata) // Only katakana is extended.
+			t.advance(1)
+			lastVowel = 0
+			continue
+		}
+		n, kana, pending := sc.match(s)
+		if n == 0 {
+			flush()
+			lastVowel = 0
+			switch p, ok := cfg.Punctuation[rune(raw[0])]; {
+			case ok:
+				putKana(p, sc)
+				lastKana = true
+			case raw[0] == ' ' && cfg.DropSpaces && lastKana:
+				// Drop it.
+			default:
+				prevByte, prevOff = int(raw[0]), t.offset
+				lastKana = false
+			}
+			t.advance(1)
+			continue
+		}
+		out := sc
+		if cfg.MixedScript {
+			out = hira
+			if isUpper(raw[:n]) {
+				out = kata
+			}
+			_, kana, _ = out.match(s[:n])
+		}
+		mark(out)
+		diagnose()
+		if r, _ := utf8.DecodeRuneInString(kana); cfg.Diagnose != nil && !lastKana && strings.ContainsRune(modifiers, r) {
+			cfg.Diagnose(Diagnostic{t.inputOffset(t.offset), raw[:n], DanglingSmallKana})
+		}
+		putKana(kana, out)
+		// Keep the case of the input, which MixedScript depends on.
+		if tail := raw[n-len(pending) : n]; lowerString(tail) == pending {
+			pending = tail
+		}
+		t.replace(n, pending)
+		lastKana = true
+		lastVowel = 0
+		if v := s[n-1]; out.katakana && vowelKana[v] != 0 {
+			lastVowel = v
+		}
+	}
+	flush()
+	diagnose()
+	if t.ch != nil {
+		close(t.ch)
+	}
+}
+
+// doubles reports whether the letter c, left unmatched immediately before
+// romaji that matches, doubles the consonant that follows and so is written
+// as a small tsu, as are the first k of kka and the t of tchi. Other
+// letters are written as they are.
+func doubles(c byte) bool {
+	return isConsonant[int(lower(c))]
+}
+
+// modifiers holds the small kana that modify the kana before them.
+const modifiers = "ぁぃぅぇぉゃゅょゎァィゥェォャュョヮ"
+
+// word returns the word, for strict mode, at the start of the input.
+func word(t *translator) string {
+	n := 0
+	for {
+		s := t.lookahead(n + 1)
+		if len(s) == n {
+			return s
+		}
+		switch c := s[n]; {
+		case isLetter(c):
+		case c == '\'' && lower(s[n-1]) == 'n':
+		case c == '-' && s[n-1] != '\'':
+		default:
+			return s[:n]
+		}
+		n++
+	}
+}
+
+// parses reports whether the word is entirely romaji when translated into sc.
+func (cfg *KanaConfig) parses(sc *script, word string) bool {
+	s := lowerString(word)
+	vowel := false // Whether the last romaji ended in a vowel.
+	for len(s) > 0 {
+		if n, _, pending := sc.match(s); n > 0 {
+			vowel = vowelKana[s[n-1]] != 0
+			s = pending + s[n:]
+			continue
+		}
+		switch {
+		case s[0] == '-':
+			_, punct := cfg.Punctuation['-']
+			if !punct && !(vowel && (sc.katakana || cfg.MixedScript)) {
+				return false
+			}
+		case len(s) > 1 && isConsonant[int(s[0])] && (s[1] == s[0] || s[0] == 't' && s[1] == 'c'):
+			vowel = false
+		default:
+			return false
+		}
+		s = s[1:]
+	}
+	return true
+}
+
+// isLetter reports whether b is an ASCII letter.
+func isLetter(b byte) bool {
+	return 'a' <= lower(b) && lower(b) <= 'z'
+}
+
+// escape copies input up to the closing escape delimiter, which it discards.
+func (cfg *KanaConfig) escape(t *translator) {
+	close := string(cfg.EscapeClose)
+	for {
+		s := t.lookahead(len(close))
+		if len(s) == 0 {
+			return
+		}
+		if s == close {
+			t.advance(len(close))
+			return
+		}
+		t.put(s[0])
+		t.advance(1)
+	}
+}
+
+// widthGetter returns a getter that reads from get, folding full-width
+// ASCII and the ideographic space outside escapes to ASCII.
+//
+// Since each folded character is two bytes shorter than the input,
+// the getter records its offset, twice, in t.shifts.
+func (cfg *KanaConfig) widthGetter(t *translator, get func() rune) func() rune {
+	escaped := false
+	return func() rune {
+		c := get()
+		switch {
+		case cfg.EscapeOpen == 0:
+		case escaped:
+			escaped = c != cfg.EscapeClose
+			return c
+		case c == cfg.EscapeOpen:
+			escaped = true
+			return c
+		}
+		if n := narrow(c); n != c {
+			off := t.offset + len(t.save) + 1
+			t.shifts = append(t.shifts, off, off)
+			c = n
+		}
+		return c
+	}
+}
+
+// longVowelGetter returns a getter that reads from get, spelling out vowels
+// marked long with a macron or circumflex, precomposed or combining, as
+// romaji that translates to a long vowel in sc: ō is ou (or oo) for hiragana
+// and o- for katakana.
+//
+// Since a combining mark makes the input one byte longer than the romaji
+// it becomes, the getter records the offsets in t.shifts.
+func (cfg *KanaConfig) longVowelGetter(t *translator, sc *script) func() rune {
+	get := t.get
+	var pending []rune
+	peekc := rune(eof)
+	escaped := false
+	return func() rune {
+		if len(pending) > 0 {
+			c := pending[0]
+			pending = pending[1:]
+			return c
+		}
+		c := peekc
+		if c == eof {
+			c = get()
+		}
+		peekc = eof
+		// Leave escaped text alone.
+		switch {
+		case cfg.EscapeOpen == 0:
+		case escaped:
+			escaped = c != cfg.EscapeClose
+			return c
+		case c == cfg.EscapeOpen:
+			escaped = true
+			return c
+		}
+		v, ok := longVowels[c]
+		if !ok {
+			if c >= utf8.RuneSelf || vowelKana[byte(unicode.ToLower(c))] == 0 {
+				return c
+			}
+			// A plain vowel, perhaps followed by a combining macron or circumflex.
+			peekc = get()
+			if peekc != '\u0304' && peekc != '\u0302' {
+				return c
+			}
+			peekc = eof
+			v = c
+			// The vowel and its long form are two bytes; the input was three.
+			t.shifts = append(t.shifts, t.offset+len(t.save)+2)
+		}
+		upper := 'A' <= v && v <= 'Z'
+		switch {
+		case sc.katakana && !cfg.MixedScript, cfg.MixedScript && upper:
+			pending = append(pending, '-')
+		case unicode.ToLower(v) == 'o' && !cfg.DoubleO:
+			pending = append(pending, v+'u'-'o')
+		default:
+			pending = append(pending, v)
+		}
+		return v
+	}
+}
+
+// longVowels maps precomposed long vowels to the plain vowel.
+var longVowels = map[rune]rune{
+	'ā': 'a',
+	'ī': 'i',
+	'ū': 'u',
+	'ē': 'e',
+	'ō': 'o',
+	'â': 'a',
+	'î': 'i',
+	'û': 'u',
+	'ê': 'e',
+	'ô': 'o',
+	'Ā': 'A',
+	'Ī': 'I',
+	'Ū': 'U',
+	'Ē': 'E',
+	'Ō': 'O',
+	'Â': 'A',
+	'Î': 'I',
+	'Û': 'U',
+	'Ê': 'E',
+	'Ô': 'O',
+}
+
+// IMEPunctuation maps ASCII punctuation to the Japanese punctuation
+// a Japanese input method produces for it.
+var IMEPunctuation = map[rune]string{
+	'.': "。",
+	',': "、",
+	'[': "「",
+	']': "」",
+	'~': "〜",
+	'?': "？",
+	'!': "！",
+	'/': "・",
+	'-': "ー",
+}
+
+// lower returns the lower-case form of the ASCII letter b, or b itself.
+func lower(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// lowerString returns s with its ASCII letters in lower case.
+func lowerString(s string) string {
+	b := []byte(s)
+	for i := range b {
+		b[i] = lower(b[i])
+	}
+	return string(b)
+}
+
+// isUpper reports whether the romaji s has no lower-case ASCII letters
+// and at least one upper-case one.
+func isUpper(s string) bool {
+	upper := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'a' <= c && c <= 'z':
+			return false
+		case 'A' <= c && c <= 'Z':
+			upper = true
+		}
+	}
+	return upper
+}
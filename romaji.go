@@ -7,40 +7,166 @@ package nihongo
 import (
 	"bytes"
 	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // romaji implements transliteration to romaji.
 type romaji struct {
-	t *translator
+	t       *translator
+	cfg     *RomajiConfig
+	capNext bool // Capitalize the next romaji written.
+	inKata  bool // Within a delimited run of katakana.
 }
 
-// Romaji translates text into romaji and returns the result.
+// A RomajiConfig controls the translation of kana into romaji.
+// The zero value translates using modified Hepburn; it is the
+// configuration used by Romaji, RomajiString and RomajiReader.
+//
+// Half-width katakana is translated as if widened by WidenKatakana,
+// so ｶﾀｶﾅ is katakana and ｶﾞ is ga. Kana iteration marks are expanded,
+// as by ExpandIterationMarks, so いすゞ is isuzu. Wapuro, which must be
+// lossless, does neither and leaves both as they are.
+type RomajiConfig struct {
+	System    System    // Romanization scheme.
+	LongVowel LongVowel // How long vowels are written.
+	// NSeparator, typically "'" or "-", is written after a syllabic ん
+	// that precedes a vowel or y, so that かんい (kan'i) is distinct from かに (kani).
+	NSeparator string
+	// AssimilateN writes ん as m before b, m and p, as traditional Hepburn does: shimbun.
+	AssimilateN bool
+	// Boundary selects what separates romaji from adjacent unconverted text.
+	Boundary Boundary
+	// Delimiter is the separator used by BoundaryDelimiter.
+	Delimiter string
+	// Particles writes the particles は, へ and を phonetically, as wa, e and o,
+	// and separates them from the surrounding words with spaces. Particles
+	// are identified heuristically; see Words.
+	Particles bool
+	// Words is a set of words, in hiragana, that helps identify particles:
+	// a は or へ following a word in the set is taken to be a particle. If it
+	// is nil, a built-in set of common pronouns, demonstratives, time nouns
+	// and places, such as わたし, これ and きょう, is used.
+	Words map[string]bool
+	// Segment, if not nil, divides each run of kana into words, which are
+	// romanized separately and written separated by spaces. Within a word,
+	// a hyphen separates the parts of a compound, and is written as is.
+	// If Particles is set, a word that is just は, へ or を is a particle.
+	// If the words do not spell out the run, the run is not divided.
+	Segment func(kana string) []string
+	// Capitalize capitalizes the first letter of the romaji beginning the
+	// text and each sentence.
+	Capitalize bool
+	// KatakanaMark selects how romanized katakana is distinguished from hiragana.
+	KatakanaMark KatakanaMark
+	// KatakanaOpen and KatakanaClose surround katakana for KatakanaDelimit.
+	KatakanaOpen, KatakanaClose string
+	// Punctuation, if not nil, maps Japanese punctuation to the punctuation
+	// that replaces it. A space is written before replacement opening
+	// punctuation, such as a quotation mark, and after closing punctuation,
+	// such as a full stop, where the text does not already have one.
+	// ALALCPunctuation is the mapping used by ALA-LC.
+	Punctuation map[rune]string
+}
+
+// A KatakanaMark selects how romanized katakana is distinguished from
+// hiragana, so that the original script can be recovered. The prolonged
+// sound mark ー belongs to the script of the kana before it.
+type KatakanaMark int
+
+const (
+	// KatakanaPlain makes no distinction: ひらがな and ヒラガナ are both hiragana.
+	KatakanaPlain KatakanaMark = iota
+	// KatakanaUpper writes katakana in upper case: ひらがなカタカナ is hiraganaKATAKANA.
+	KatakanaUpper
+	// KatakanaDelimit surrounds each run of katakana with the configured
+	// KatakanaOpen and KatakanaClose strings.
+	KatakanaDelimit
+)
+
+// A Boundary selects what is written between romaji and adjacent text that
+// was not converted, such as kanji, digits or punctuation.
+type Boundary int
+
+const (
+	// BoundarySpace separates them with a space: a日本語ひらがな is a日本語 hiragana.
+	BoundarySpace Boundary = iota
+	// BoundaryNone writes nothing: ポケモンGO is pokemonGO.
+	BoundaryNone
+	// BoundaryDelimiter writes the configured Delimiter.
+	BoundaryDelimiter
+	// BoundarySmart writes a space only when the unconverted text is a letter,
+	// never next to punctuation, digits or spaces.
+	BoundarySmart
+)
+
+// A LongVowel selects how long vowels are written in romaji. A long vowel
+// is a vowel followed by the same vowel (おお, ああ), お followed by う,
+// or any vowel followed by the prolonged sound mark ー. Following Hepburn,
+// えい and いい are not treated as long vowels.
+type LongVowel int
+
+const (
+	// AsWritten spells long vowels as the kana do: とうきょう is toukyou.
+	// The prolonged sound mark repeats the vowel before it: コーヒー is koohii.
+	AsWritten LongVowel = iota
+	// Macron marks long vowels with a macron: tōkyō.
+	Macron
+	// Circumflex marks long vowels with a circumflex, as in Kunrei-shiki: tôkyô.
+	Circumflex
+	// Doubled writes long vowels twice: tookyoo.
+	Doubled
+	// Oh writes a long o as oh and other long vowels as a single vowel,
+	// as Japanese passports do: tohkyoh.
+	Oh
+)
+
+// Romaji translates text into romaji using modified Hepburn and returns the result.
 func Romaji(text []byte) []byte {
+	return new(RomajiConfig).Romaji(text)
+}
+
+// RomajiString translates text into romaji using modified Hepburn and returns the result.
+func RomajiString(text string) string {
+	return new(RomajiConfig).RomajiString(text)
+}
+
+// RomajiReader returns an io.Reader that will translate its input into romaji using modified Hepburn.
+func RomajiReader(rd io.Reader) io.Reader {
+	return new(RomajiConfig).RomajiReader(rd)
+}
+
+// Romaji translates text into romaji as configured by c and returns the result.
+func (c *RomajiConfig) Romaji(text []byte) []byte {
 	var buf bytes.Buffer
 	r := romaji{
-		t: newTranslator(bytesGetter(text), bufPutter(&buf), nil),
+		t:   newTranslator(bytesGetter(text), bufPutter(&buf), nil),
+		cfg: c,
 	}
-	translateRomaji(r.t)
+	r.translate()
 	return buf.Bytes()
 }
 
-// RomajiString translates text into romaji and returns the result.
-func RomajiString(text string) string {
+// RomajiString translates text into romaji as configured by c and returns the result.
+func (c *RomajiConfig) RomajiString(text string) string {
 	var buf bytes.Buffer
 	r := romaji{
-		t: newTranslator(stringGetter(text), bufPutter(&buf), nil),
+		t:   newTranslator(stringGetter(text), bufPutter(&buf), nil),
+		cfg: c,
 	}
-	translateRomaji(r.t)
+	r.translate()
 	return buf.String()
 }
 
-// RomajiReader returns an io.Reader that will translate its input into romaji.
-func RomajiReader(rd io.Reader) io.Reader {
+// RomajiReader returns an io.Reader that will translate its input into romaji as configured by c.
+func (c *RomajiConfig) RomajiReader(rd io.Reader) io.Reader {
 	ch := make(chan byte, 100)
 	r := &romaji{
-		t: newTranslator(readerGetter(rd), chanPutter(ch), ch),
+		t:   newTranslator(readerGetter(rd), chanPutter(ch), ch),
+		cfg: c,
 	}
-	go translateRomaji(r.t)
+	go r.translate()
 	return r
 }
 
@@ -48,211 +174,513 @@ func (r *romaji) Read(p []byte) (int, error) {
 	return r.t.Read(p)
 }
 
-func translateRomaji(t *translator) {
+func (r *romaji) translate() {
+	t := r.t
+	if r.cfg.System != Wapuro {
+		t.get = iterationGetter(halfWidthGetter(t.get))
+	}
+	sys := r.cfg.System.romanization()
+	prev := rune(eof) // The most recent unconverted rune.
 	prevKana := false
-	skip := false
-	for first := true; ; first = false {
-		r := t.next()
-		if r == eof {
+	spaced := false // A space followed the punctuation just written.
+	r.capNext = r.cfg.Capitalize
+	for {
+		c := t.next()
+		if c == eof {
 			break
 		}
-		k, ok := kana[r]
-		if skip {
-			skip = false
-			continue
-		}
-		if !ok {
+		if _, ok := sys.kana[toHiragana(c)]; !ok && toHiragana(c) != 'っ' {
+			out, mapped := r.cfg.Punctuation[c]
+			if !mapped {
+				out = string(c)
+			}
+			first, _ := utf8.DecodeRuneInString(out)
+			last, _ := utf8.DecodeLastRuneInString(out)
 			if prevKana {
+				r.boundary(first)
+			}
+			if mapped && opens(first) && !spaced && (prevKana || prev != eof && !unicode.IsSpace(prev)) {
 				t.put(' ')
 			}
-			t.putRune(r)
+			t.putString(out)
+			spaced = false
+			if mapped && closes(last) {
+				if next := t.peek(); next != eof && !unicode.IsSpace(next) && !unicode.IsPunct(next) {
+					t.put(' ')
+					spaced = true
+				}
+			}
+			prev = c
 			prevKana = false
+			if r.cfg.Capitalize {
+				for _, c := range out {
+					if strings.ContainsRune("。！？.!?", c) {
+						r.capNext = true
+					} else if unicode.IsLetter(c) {
+						r.capNext = false
+					}
+				}
+			}
 			continue
 		}
-		if !first && !prevKana {
-			t.put(' ')
+		if prev != eof && !spaced {
+			r.boundary(prev)
 		}
+		spaced = false
 		prevKana = true
-		// Is there a modifier?
-		if small[t.peek()] {
-			skip = true
-			r2 := t.next()
-			k2, ok := mod[r2]
-			if ok {
-				t.putString(k[:len(k)-1])
-				t.putString(k2[1:])
-				continue
+		run := []rune{c}
+		for {
+			c = t.peek()
+			if _, ok := sys.kana[toHiragana(c)]; !ok && !small[c] && c != 'ー' {
+				break
 			}
-			k2, ok = vowel[r2]
-			if ok {
-				t.putString(k)
-				t.put('-')
-				continue
-			}
-			// Otherwise it's just odd.
-			t.put('<')
-			t.putString(k)
-			t.put('.')
-			t.putString(odd[r2])
-			t.put('>')
+			run = append(run, t.next())
+		}
+		if r.cfg.System == Wapuro {
+			r.putWapuro(run)
 			continue
 		}
-		t.putString(k)
+		if r.cfg.Segment != nil && r.putWords(run) {
+			continue
+		}
+		syls := sys.syllables(run)
+		if r.cfg.Particles {
+			r.markParticles(syls, prev)
+		}
+		r.putRun(syls)
 	}
 	if t.ch != nil {
 		close(t.ch)
 	}
 }
 
-var kana = map[rune]string{
-	'あ': "a",
-	'い': "i",
-	'う': "u",
-	'え': "e",
-	'お': "o",
-	'か': "ka",
-	'が': "ga",
-	'き': "ki",
-	'ぎ': "gi",
-	'く': "ku",
-	'ぐ': "gu",
-	'け': "ke",
-	'げ': "ge",
-	'こ': "ko",
-	'ご': "go",
-	'さ': "sa",
-	'ざ': "za",
-	'し': "shi",
-	'じ': "zi",
-	'す': "su",
-	'ず': "zu",
-	'せ': "se",
-	'ぜ': "ze",
-	'そ': "so",
-	'ぞ': "zo",
-	'た': "ta",
-	'だ': "da",
-	'ち': "chi",
-	'ぢ': "di",
-	'つ': "tsu",
-	'づ': "du",
-	'て': "te",
-	'で': "de",
-	'と': "to",
-	'ど': "do",
-	'な': "na",
-	'に': "ni",
-	'ぬ': "nu",
-	'ね': "ne",
-	'の': "no",
-	'は': "ha",
-	'ば': "va",
-	'ぱ': "pa",
-	'ひ': "hi",
-	'び': "vi",
-	'ぴ': "pi",
-	'ふ': "fu",
-	'ぶ': "bu",
-	'ぷ': "pu",
-	'へ': "he",
-	'べ': "ve",
-	'ぺ': "pe",
-	'ほ': "ho",
-	'ぼ': "vo",
-	'ぽ': "po",
-	'ま': "ma",
-	'み': "mi",
-	'む': "mu",
-	'め': "me",
-	'も': "mo",
-	'や': "ya",
-	'ゆ': "yu",
-	'よ': "yo",
-	'ら': "ra",
-	'り': "ri",
-	'る': "ru",
-	'れ': "re",
-	'ろ': "ro",
-	'わ': "wa",
-	'ゐ': "wi",
-	'ゑ': "we",
-	'を': "wo",
-	'ん': "n",
-	'ゔ': "vu",
-	'ア': "a",
-	'イ': "i",
-	'ウ': "u",
-	'エ': "e",
-	'オ': "o",
-	'カ': "ka",
-	'ガ': "ga",
-	'キ': "ki",
-	'ギ': "gi",
-	'ク': "ku",
-	'グ': "gu",
-	'ケ': "ke",
-	'ゲ': "ge",
-	'コ': "ko",
-	'ゴ': "go",
-	'サ': "sa",
-	'ザ': "za",
-	'シ': "shi",
-	'ジ': "zi",
-	'ス': "su",
-	'ズ': "zu",
-	'セ': "se",
-	'ゼ': "ze",
-	'ソ': "so",
-	'ゾ': "zo",
-	'タ': "ta",
-	'ダ': "da",
-	'チ': "chi",
-	'ヂ': "di",
-	'ツ': "tsu",
-	'ヅ': "du",
-	'テ': "te",
-	'デ': "de",
-	'ト': "to",
-	'ド': "do",
-	'ナ': "na",
-	'ニ': "ni",
-	'ヌ': "nu",
-	'ネ': "ne",
-	'ノ': "no",
-	'ハ': "ha",
-	'バ': "va",
-	'パ': "pa",
-	'ヒ': "hi",
-	'ビ': "vi",
-	'ピ': "pi",
-	'フ': "fu",
-	'ブ': "bu",
-	'プ': "pu",
-	'ヘ': "he",
-	'ベ': "ve",
-	'ペ': "pe",
-	'ホ': "ho",
-	'ボ': "vo",
-	'ポ': "po",
-	'マ': "ma",
-	'ミ': "mi",
-	'ム': "mu",
-	'メ': "me",
-	'モ': "mo",
-	'ヤ': "ya",
-	'ユ': "yu",
-	'ヨ': "yo",
-	'ラ': "ra",
-	'リ': "ri",
-	'ル': "ru",
-	'レ': "re",
-	'ロ': "ro",
-	'ワ': "wa",
-	'ヰ': "wi",
-	'ヱ': "we",
-	'ヲ': "wo",
-	'ン': "n",
-	'ヴ': "vu",
+// opens reports whether r is opening punctuation, such as a bracket or quotation mark.
+func opens(r rune) bool {
+	return unicode.In(r, unicode.Ps, unicode.Pi)
+}
+
+// closes reports whether r is punctuation that ends a clause, a sentence or a quotation.
+func closes(r rune) bool {
+	return unicode.In(r, unicode.Pe, unicode.Pf) || strings.ContainsRune(".,;:!?", r)
+}
+
+// boundary writes the separator between romaji and the adjacent unconverted rune c.
+func (r *romaji) boundary(c rune) {
+	if r.cfg.System == Wapuro {
+		return
+	}
+	switch r.cfg.Boundary {
+	case BoundarySpace:
+		r.t.put(' ')
+	case BoundaryDelimiter:
+		r.t.putString(r.cfg.Delimiter)
+	case BoundarySmart:
+		if unicode.IsLetter(c) {
+			r.t.put(' ')
+		}
+	}
+}
+
+// putWapuro writes a run of kana using the Wapuro system.
+func (r *romaji) putWapuro(run []rune) {
+	prev := ""
+	kata := false
+	for i := 0; i < len(run); i++ {
+		c := toHiragana(run[i])
+		if c != 'ー' {
+			kata = isKatakana(string(run[i]))
+		}
+		var next rune
+		if i+1 < len(run) {
+			next = toHiragana(run[i+1])
+		}
+		s, ok := wapuroYoon[string(c)+string(next)]
+		switch {
+		case ok:
+			i++
+		case c == 'ん':
+			// Before a vowel or y, nn would be read as ん followed by a na-row kana.
+			s = "nn"
+			if n := wapuroKana[next]; n != "" && strings.IndexByte("aiueoy", n[0]) >= 0 {
+				s = "n'"
+			}
+		case c == 'ー':
+			// Katakana reads a hyphen after a vowel as ー.
+			s = "ー"
+			if n := len(prev); n > 0 && vowelKana[prev[n-1]] != 0 && isKatakana(string(run[i-1])) {
+				s = "-"
+			}
+		default:
+			s = wapuroKana[c]
+		}
+		r.script(kata)
+		r.putRoman(s, kata)
+		prev = s
+	}
+	r.script(false)
+}
+
+// syllable is a unit of romanized kana: a kana and any small kana modifying it.
+type syllable struct {
+	kana     rune   // The leading kana, folded to hiragana.
+	src      string // The kana as written.
+	roman    string // Its romanization.
+	particle bool   // Whether it is the particle は, へ or を.
+}
+
+// syllables splits a run of kana, which begins with a full-sized kana or a small tsu, into syllables.
+func (sys *romanization) syllables(run []rune) []syllable {
+	syls := make([]syllable, 0, len(run))
+	for i := 0; i < len(run); i++ {
+		c := toHiragana(run[i])
+		k, ok := sys.kana[c]
+		if !ok {
+			// A prolonged sound mark, a small tsu, or a small kana with no kana to modify.
+			syls = append(syls, syllable{kana: c, src: string(run[i]), roman: string(run[i])})
+			continue
+		}
+		start := i
+		// Is there a modifier? A small tsu is not one; it doubles what follows.
+		if i+1 < len(run) && small[run[i+1]] && toHiragana(run[i+1]) != 'っ' {
+			i++
+			c2 := run[i]
+			if y, ok := sys.yoon[string(c)+string(toHiragana(c2))]; ok {
+				k = y
+			} else if _, ok := vowel[c2]; ok {
+				k += "-"
+			} else {
+				// Otherwise it's just odd.
+				k = "<" + k + "." + odd[c2] + ">"
+			}
+		}
+		syls = append(syls, syllable{kana: c, src: string(run[start : i+1]), roman: k})
+	}
+	return syls
+}
+
+// markParticles marks the syllables of a run of kana that appear to be the
+// particles は, へ and を and gives them their phonetic spelling. The rune prev
+// is the unconverted text preceding the run, or eof.
+//
+// Since を is only ever a particle, it is always marked. A hiragana は or へ is
+// marked when it follows kanji and does not begin a common word, ends the run
+// and does not repeat the kana before it, lies between hiragana and katakana,
+// follows で, に or と, or follows a word in the configured list. The は ending
+// a greeting such as こんにちは is spelled wa but left part of the word.
+func (r *romaji) markParticles(syls []syllable, prev rune) {
+	for i := range syls {
+		s := &syls[i]
+		spelling, ok := particles[s.src]
+		if !ok {
+			continue
+		}
+		switch {
+		case s.src == "を":
+			s.particle = true
+		case endsWith(syls[:i+1], greetings):
+			s.roman = spelling
+			continue
+		case i == 0:
+			s.particle = unicode.Is(unicode.Han, prev) && !beginsWith(syls, wordStarts)
+		case i == len(syls)-1:
+			s.particle = syls[i-1].src != s.src
+		case isKatakana(syls[i-1].src) != isKatakana(syls[i+1].src):
+			s.particle = true
+		case syls[i-1].src == "で" || syls[i-1].src == "に" || syls[i-1].src == "と":
+			s.particle = true
+		default:
+			words := r.cfg.Words
+			if words == nil {
+				words = defaultWords
+			}
+			s.particle = endsWith(syls[:i], words)
+		}
+		if s.particle {
+			s.roman = spelling
+		}
+	}
+}
+
+var particles = map[string]string{
+	"は": "wa",
+	"へ": "e",
+	"を": "o",
+}
+
+// defaultWords holds common words often followed by a particle: pronouns,
+// demonstratives, time nouns and a few places. It is used when
+// RomajiConfig.Words is nil.
+var defaultWords = map[string]bool{
+	"わたし":   true,
+	"わたくし":  true,
+	"ぼく":    true,
+	"おれ":    true,
+	"あなた":   true,
+	"きみ":    true,
+	"かれ":    true,
+	"かのじょ":  true,
+	"みんな":   true,
+	"これ":    true,
+	"それ":    true,
+	"あれ":    true,
+	"どれ":    true,
+	"ここ":    true,
+	"そこ":    true,
+	"あそこ":   true,
+	"どこ":    true,
+	"こちら":   true,
+	"そちら":   true,
+	"あちら":   true,
+	"どちら":   true,
+	"だれ":    true,
+	"なに":    true,
+	"きょう":   true,
+	"あした":   true,
+	"あす":    true,
+	"きのう":   true,
+	"けさ":    true,
+	"こんや":   true,
+	"ことし":   true,
+	"らいねん":  true,
+	"きょねん":  true,
+	"まいにち":  true,
+	"がっこう":  true,
+	"えき":    true,
+	"うち":    true,
+	"いえ":    true,
+	"かいしゃ":  true,
+	"にほん":   true,
+	"とうきょう": true,
+}
+
+// greetings holds the greetings that end in は, which is not a particle
+// in them, or at least is not written as one.
+var greetings = map[string]bool{
+	"こんにちは": true,
+	"こんばんは": true,
+}
+
+// wordStarts holds common words beginning with は or へ, which after kanji
+// are more likely the start of a word than a particle: 日本はじめて.
+var wordStarts = map[string]bool{
+	"はじめ":  true,
+	"はじま":  true,
+	"はいる":  true,
+	"はいっ":  true,
+	"はやい":  true,
+	"はやく":  true,
+	"はっきり": true,
+	"はず":   true,
+	"はなし":  true,
+	"はなす":  true,
+	"はたら":  true,
+	"へや":   true,
+	"へん":   true,
+	"へた":   true,
+}
+
+// endsWith reports whether the syllables end with a word in the set.
+func endsWith(syls []syllable, set map[string]bool) bool {
+	word := ""
+	for i := len(syls) - 1; i >= 0; i-- {
+		word = syls[i].src + word
+		if set[toHiraganaString(word)] {
+			return true
+		}
+	}
+	return false
+}
+
+// beginsWith reports whether the syllables begin with a word in the set.
+func beginsWith(syls []syllable, set map[string]bool) bool {
+	word := ""
+	for _, s := range syls {
+		word += s.src
+		if set[toHiraganaString(word)] {
+			return true
+		}
+	}
+	return false
+}
+
+// isKatakana reports whether s begins with katakana.
+func isKatakana(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return 'ァ' <= r && r <= 'ヺ' || r == 'ー'
+}
+
+// toHiraganaString returns s with its katakana folded to hiragana.
+func toHiraganaString(s string) string {
+	return strings.Map(toHiragana, s)
+}
+
+// putRun writes the syllables of a run of kana.
+func (r *romaji) putRun(syls []syllable) {
+	prevVowel := byte(0) // The vowel ending the previous syllable.
+	kata := false
+	for i := 0; i < len(syls); i++ {
+		if syls[i].kana != 'ー' {
+			kata = isKatakana(syls[i].src)
+		}
+		if !kata {
+			r.script(false)
+		}
+		if i > 0 && (syls[i].particle || syls[i-1].particle) {
+			// Particles are separate words.
+			r.t.put(' ')
+		}
+		r.script(kata)
+		s := syls[i].roman
+		switch syls[i].kana {
+		case 'ー':
+			// Repeat the preceding vowel, if there is one.
+			s = "-"
+			if prevVowel != 0 {
+				s = string(prevVowel)
+			}
+		case 'っ':
+			s = geminate(syls[i+1:])
+		case 'ん':
+			if i+1 < len(syls) && !syls[i+1].particle {
+				s = r.syllabicN(s, syls[i+1].roman)
+			}
+		}
+		v := endVowel(syls[i], s)
+		if i+1 < len(syls) && r.cfg.LongVowel != AsWritten && !syls[i].particle && !syls[i+1].particle {
+			if s[len(s)-1] == v && lengthens(v, syls[i+1]) {
+				s = s[:len(s)-1] + r.cfg.LongVowel.vowel(v)
+				i++
+			}
+		}
+		r.putRoman(s, kata)
+		prevVowel = v
+	}
+	r.script(false)
+}
+
+// script writes the delimiter, if any is configured, that begins or ends
+// a run of katakana, according to whether the next romaji is katakana.
+func (r *romaji) script(kata bool) {
+	if r.cfg.KatakanaMark != KatakanaDelimit || kata == r.inKata {
+		return
+	}
+	if kata {
+		r.t.putString(r.cfg.KatakanaOpen)
+	} else {
+		r.t.putString(r.cfg.KatakanaClose)
+	}
+	r.inKata = kata
+}
+
+// putRoman writes romaji s, romanized from katakana if kata is set,
+// capitalizing it if required.
+func (r *romaji) putRoman(s string, kata bool) {
+	if kata && r.cfg.KatakanaMark == KatakanaUpper {
+		s = strings.ToUpper(s)
+	}
+	if r.capNext && s != "" {
+		c, w := utf8.DecodeRuneInString(s)
+		r.t.putRune(unicode.ToUpper(c))
+		s = s[w:]
+		r.capNext = false
+	}
+	r.t.putString(s)
+}
+
+// geminate returns the spelling of a small tsu followed by the syllables in next:
+// the consonant it doubles, t before ch as Hepburn prescribes, or an apostrophe
+// if there is nothing to double.
+func geminate(next []syllable) string {
+	if len(next) == 0 || next[0].kana == 'ん' || next[0].particle {
+		return "'"
+	}
+	s := next[0].roman
+	if strings.HasPrefix(s, "ch") {
+		return "t"
+	}
+	if c := s[0]; 'a' <= c && c <= 'z' && vowelKana[c] == 0 {
+		return s[:1]
+	}
+	return "'"
+}
+
+// syllabicN returns the spelling n of a syllabic ん followed by next.
+func (r *romaji) syllabicN(n, next string) string {
+	switch next[0] {
+	case 'a', 'i', 'u', 'e', 'o', 'y':
+		return n + r.cfg.NSeparator
+	case 'b', 'm', 'p':
+		if r.cfg.AssimilateN {
+			return "m"
+		}
+	}
+	return n
+}
+
+// endVowel returns the vowel that ends syllable s, spelled as roman, or 0 if
+// it does not end in a vowel. A syllable with a small vowel, such as テァ,
+// which has no spelling of its own, ends in the small vowel.
+func endVowel(s syllable, roman string) byte {
+	if roman != "" && vowelKana[roman[len(roman)-1]] != 0 {
+		return roman[len(roman)-1]
+	}
+	r, _ := utf8.DecodeLastRuneInString(s.src)
+	if v, ok := vowel[r]; ok {
+		return v[0]
+	}
+	return 0
+}
+
+// lengthens reports whether syllable s lengthens a preceding vowel v.
+func lengthens(v byte, s syllable) bool {
+	if s.kana == 'ー' {
+		return vowelKana[v] != 0
+	}
+	if len(s.roman) != 1 {
+		return false
+	}
+	return v != 'i' && s.kana == vowelKana[v] || v == 'o' && s.kana == 'う'
+}
+
+var vowelKana = map[byte]rune{
+	'a': 'あ',
+	'i': 'い',
+	'u': 'う',
+	'e': 'え',
+	'o': 'お',
+}
+
+// vowel returns the spelling of the long form of vowel v.
+func (l LongVowel) vowel(v byte) string {
+	switch l {
+	case Macron:
+		return macron[v]
+	case Circumflex:
+		return circumflex[v]
+	case Doubled:
+		return string([]byte{v, v})
+	case Oh:
+		if v == 'o' {
+			return "oh"
+		}
+	}
+	return string(v)
+}
+
+var macron = map[byte]string{
+	'a': "ā",
+	'i': "ī",
+	'u': "ū",
+	'e': "ē",
+	'o': "ō",
+}
+
+var circumflex = map[byte]string{
+	'a': "â",
+	'i': "î",
+	'u': "û",
+	'e': "ê",
+	'o': "ô",
 }
 
 var small = map[rune]bool{
@@ -297,21 +725,9 @@ var vowel = map[rune]string{
 	'ォ': "o",
 }
 
-var mod = map[rune]string{
-	'ゃ': "ya",
-	'ゅ': "yu",
-	'ょ': "yo",
-
-	'ャ': "ya",
-	'ュ': "yu",
-	'ョ': "yo",
-}
-
 var odd = map[rune]string{
-	'っ': "hold",  // tsu == hold consonant
 	'ゕ': "count", // ka == counting mark
 	'ゖ': "count", // ke == counting mark
-	'ッ': "hold",  // tsu == hold consonant
 	'ヵ': "count", // ka == counting mark
 	'ヶ': "count", // ke == counting mark
 }
@@ -0,0 +1,711 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+// A System selects the romanization scheme used when translating kana into romaji.
+type System int
+
+const (
+	// Hepburn is modified Hepburn: し is shi, ち is chi, つ is tsu, ふ is fu, じ is ji.
+	Hepburn System = iota
+	// Kunrei is Kunrei-shiki (ISO 3602): し is si, ち is ti, つ is tu, ふ is hu, じ is zi.
+	// Sounds in loanwords that it does not cover are spelled as an input
+	// method types them, since ti and du already spell ち and づ: ティ is thi,
+	// トゥ is twu and ディ is dhi.
+	Kunrei
+	// Nihon is Nihon-shiki, which is Kunrei-shiki but keeps ぢ and づ distinct as di and du.
+	Nihon
+	// Wapuro is the lossless spelling typed into a Japanese input method:
+	// ん is nn, or n' before a vowel or y; small kana are spelled with x,
+	// as in xtu and xya; long vowels are kept as written; and ー following
+	// katakana that ends in a vowel is a hyphen. Iteration marks and
+	// half-width katakana are left as they are. For text that contains
+	// no ASCII letters, hyphens or apostrophes other than in its kana,
+	// Hiragana undoes Wapuro romanization of hiragana and Katakana undoes
+	// it for katakana:
+	//	HiraganaString(c.RomajiString(x)) == x
+	// Wapuro ignores all other RomajiConfig settings except KatakanaMark.
+	Wapuro
+)
+
+func (s System) String() string {
+	switch s {
+	case Hepburn:
+		return "Hepburn"
+	case Kunrei:
+		return "Kunrei"
+	case Nihon:
+		return "Nihon"
+	case Wapuro:
+		return "Wapuro"
+	}
+	return "System(?)"
+}
+
+// romanization holds the tables for a System. Both are keyed by hiragana;
+// katakana is folded to hiragana before lookup.
+type romanization struct {
+	kana map[rune]string   // A single kana.
+	yoon map[string]string // A kana followed by a small ya, yu, yo or wa.
+}
+
+var systems = [...]romanization{
+	Hepburn: {hepburnKana, hepburnYoon},
+	Kunrei:  {kunreiKana, kunreiYoon},
+	Nihon:   {nihonKana, nihonYoon},
+	Wapuro:  {wapuroKana, wapuroYoon},
+}
+
+// romanization returns the tables for s, defaulting to Hepburn.
+func (s System) romanization() *romanization {
+	if s < 0 || int(s) >= len(systems) {
+		s = Hepburn
+	}
+	return &systems[s]
+}
+
+// toHiragana returns the hiragana corresponding to katakana r, or r itself.
+func toHiragana(r rune) rune {
+	if 'ァ' <= r && r <= 'ヶ' {
+		return r - ('ァ' - 'ぁ')
+	}
+	return r
+}
+
+// toKatakana returns the katakana corresponding to hiragana r, or r itself.
+func toKatakana(r rune) rune {
+	if 'ぁ' <= r && r <= 'ゖ' {
+		return r + ('ァ' - 'ぁ')
+	}
+	return r
+}
+
+var hepburnKana = map[rune]string{
+	'あ': "a",
+	'い': "i",
+	'う': "u",
+	'え': "e",
+	'お': "o",
+	'か': "ka",
+	'き': "ki",
+	'く': "ku",
+	'け': "ke",
+	'こ': "ko",
+	'が': "ga",
+	'ぎ': "gi",
+	'ぐ': "gu",
+	'げ': "ge",
+	'ご': "go",
+	'さ': "sa",
+	'し': "shi",
+	'す': "su",
+	'せ': "se",
+	'そ': "so",
+	'ざ': "za",
+	'じ': "ji",
+	'ず': "zu",
+	'ぜ': "ze",
+	'ぞ': "zo",
+	'た': "ta",
+	'ち': "chi",
+	'つ': "tsu",
+	'て': "te",
+	'と': "to",
+	'だ': "da",
+	'ぢ': "ji",
+	'づ': "zu",
+	'で': "de",
+	'ど': "do",
+	'な': "na",
+	'に': "ni",
+	'ぬ': "nu",
+	'ね': "ne",
+	'の': "no",
+	'は': "ha",
+	'ひ': "hi",
+	'ふ': "fu",
+	'へ': "he",
+	'ほ': "ho",
+	'ば': "ba",
+	'び': "bi",
+	'ぶ': "bu",
+	'べ': "be",
+	'ぼ': "bo",
+	'ぱ': "pa",
+	'ぴ': "pi",
+	'ぷ': "pu",
+	'ぺ': "pe",
+	'ぽ': "po",
+	'ま': "ma",
+	'み': "mi",
+	'む': "mu",
+	'め': "me",
+	'も': "mo",
+	'や': "ya",
+	'ゆ': "yu",
+	'よ': "yo",
+	'ら': "ra",
+	'り': "ri",
+	'る': "ru",
+	'れ': "re",
+	'ろ': "ro",
+	'わ': "wa",
+	'ゐ': "wi",
+	'ゑ': "we",
+	'を': "wo",
+	'ん': "n",
+	'ゔ': "vu",
+}
+
+var kunreiKana = map[rune]string{
+	'あ': "a",
+	'い': "i",
+	'う': "u",
+	'え': "e",
+	'お': "o",
+	'か': "ka",
+	'き': "ki",
+	'く': "ku",
+	'け': "ke",
+	'こ': "ko",
+	'が': "ga",
+	'ぎ': "gi",
+	'ぐ': "gu",
+	'げ': "ge",
+	'ご': "go",
+	'さ': "sa",
+	'し': "si",
+	'す': "su",
+	'せ': "se",
+	'そ': "so",
+	'ざ': "za",
+	'じ': "zi",
+	'ず': "zu",
+	'ぜ': "ze",
+	'ぞ': "zo",
+	'た': "ta",
+	'ち': "ti",
+	'つ': "tu",
+	'て': "te",
+	'と': "to",
+	'だ': "da",
+	'ぢ': "zi",
+	'づ': "zu",
+	'で': "de",
+	'ど': "do",
+	'な': "na",
+	'に': "ni",
+	'ぬ': "nu",
+	'ね': "ne",
+	'の': "no",
+	'は': "ha",
+	'ひ': "hi",
+	'ふ': "hu",
+	'へ': "he",
+	'ほ': "ho",
+	'ば': "ba",
+	'び': "bi",
+	'ぶ': "bu",
+	'べ': "be",
+	'ぼ': "bo",
+	'ぱ': "pa",
+	'ぴ': "pi",
+	'ぷ': "pu",
+	'ぺ': "pe",
+	'ぽ': "po",
+	'ま': "ma",
+	'み': "mi",
+	'む': "mu",
+	'め': "me",
+	'も': "mo",
+	'や': "ya",
+	'ゆ': "yu",
+	'よ': "yo",
+	'ら': "ra",
+	'り': "ri",
+	'る': "ru",
+	'れ': "re",
+	'ろ': "ro",
+	'わ': "wa",
+	'ゐ': "i",
+	'ゑ': "e",
+	'を': "o",
+	'ん': "n",
+	'ゔ': "vu",
+}
+
+var nihonKana = map[rune]string{
+	'あ': "a",
+	'い': "i",
+	'う': "u",
+	'え': "e",
+	'お': "o",
+	'か': "ka",
+	'き': "ki",
+	'く': "ku",
+	'け': "ke",
+	'こ': "ko",
+	'が': "ga",
+	'ぎ': "gi",
+	'ぐ': "gu",
+	'げ': "ge",
+	'ご': "go",
+	'さ': "sa",
+	'し': "si",
+	'す': "su",
+	'せ': "se",
+	'そ': "so",
+	'ざ': "za",
+	'じ': "zi",
+	'ず': "zu",
+	'ぜ': "ze",
+	'ぞ': "zo",
+	'た': "ta",
+	'ち': "ti",
+	'つ': "tu",
+	'て': "te",
+	'と': "to",
+	'だ': "da",
+	'ぢ': "di",
+	'づ': "du",
+	'で': "de",
+	'ど': "do",
+	'な': "na",
+	'に': "ni",
+	'ぬ': "nu",
+	'ね': "ne",
+	'の': "no",
+	'は': "ha",
+	'ひ': "hi",
+	'ふ': "hu",
+	'へ': "he",
+	'ほ': "ho",
+	'ば': "ba",
+	'び': "bi",
+	'ぶ': "bu",
+	'べ': "be",
+	'ぼ': "bo",
+	'ぱ': "pa",
+	'ぴ': "pi",
+	'ぷ': "pu",
+	'ぺ': "pe",
+	'ぽ': "po",
+	'ま': "ma",
+	'み': "mi",
+	'む': "mu",
+	'め': "me",
+	'も': "mo",
+	'や': "ya",
+	'ゆ': "yu",
+	'よ': "yo",
+	'ら': "ra",
+	'り': "ri",
+	'る': "ru",
+	'れ': "re",
+	'ろ': "ro",
+	'わ': "wa",
+	'ゐ': "wi",
+	'ゑ': "we",
+	'を': "wo",
+	'ん': "n",
+	'ゔ': "vu",
+}
+
+var hepburnYoon = map[string]string{
+	"きゃ": "kya",
+	"きゅ": "kyu",
+	"きょ": "kyo",
+
+	"ぎゃ": "gya",
+	"ぎゅ": "gyu",
+	"ぎょ": "gyo",
+
+	"しゃ": "sha",
+	"しゅ": "shu",
+	"しょ": "sho",
+
+	"じゃ": "ja",
+	"じゅ": "ju",
+	"じょ": "jo",
+
+	"ちゃ": "cha",
+	"ちゅ": "chu",
+	"ちょ": "cho",
+
+	"ぢゃ": "ja",
+	"ぢゅ": "ju",
+	"ぢょ": "jo",
+
+	"にゃ": "nya",
+	"にゅ": "nyu",
+	"にょ": "nyo",
+
+	"ひゃ": "hya",
+	"ひゅ": "hyu",
+	"ひょ": "hyo",
+
+	"びゃ": "bya",
+	"びゅ": "byu",
+	"びょ": "byo",
+
+	"ぴゃ": "pya",
+	"ぴゅ": "pyu",
+	"ぴょ": "pyo",
+
+	"みゃ": "mya",
+	"みゅ": "myu",
+	"みょ": "myo",
+
+	"りゃ": "rya",
+	"りゅ": "ryu",
+	"りょ": "ryo",
+
+	// Foreign sounds, mostly in katakana loanwords.
+	"いぇ": "ye",
+
+	"うぃ": "wi",
+	"うぇ": "we",
+	"うぉ": "wo",
+
+	"ゔぁ": "va",
+	"ゔぃ": "vi",
+	"ゔぇ": "ve",
+	"ゔぉ": "vo",
+
+	"しぇ": "she",
+	"じぇ": "je",
+	"ちぇ": "che",
+
+	"つぁ": "tsa",
+	"つぃ": "tsi",
+	"つぇ": "tse",
+	"つぉ": "tso",
+
+	"てぃ": "ti",
+	"とぅ": "tu",
+	"てゅ": "tyu",
+
+	"でぃ": "di",
+	"どぅ": "du",
+	"でゅ": "dyu",
+
+	"ふぁ": "fa",
+	"ふぃ": "fi",
+	"ふぇ": "fe",
+	"ふぉ": "fo",
+	"ふゅ": "fyu",
+}
+
+var kunreiYoon = map[string]string{
+	"きゃ": "kya",
+	"きゅ": "kyu",
+	"きょ": "kyo",
+
+	"ぎゃ": "gya",
+	"ぎゅ": "gyu",
+	"ぎょ": "gyo",
+
+	"しゃ": "sya",
+	"しゅ": "syu",
+	"しょ": "syo",
+
+	"じゃ": "zya",
+	"じゅ": "zyu",
+	"じょ": "zyo",
+
+	"ちゃ": "tya",
+	"ちゅ": "tyu",
+	"ちょ": "tyo",
+
+	"ぢゃ": "zya",
+	"ぢゅ": "zyu",
+	"ぢょ": "zyo",
+
+	"にゃ": "nya",
+	"にゅ": "nyu",
+	"にょ": "nyo",
+
+	"ひゃ": "hya",
+	"ひゅ": "hyu",
+	"ひょ": "hyo",
+
+	"びゃ": "bya",
+	"びゅ": "byu",
+	"びょ": "byo",
+
+	"ぴゃ": "pya",
+	"ぴゅ": "pyu",
+	"ぴょ": "pyo",
+
+	"みゃ": "mya",
+	"みゅ": "myu",
+	"みょ": "myo",
+
+	"りゃ": "rya",
+	"りゅ": "ryu",
+	"りょ": "ryo",
+
+	// Foreign sounds, mostly in katakana loanwords.
+	"いぇ": "ye",
+
+	"うぃ": "wi",
+	"うぇ": "we",
+	"うぉ": "wo",
+
+	"ゔぁ": "va",
+	"ゔぃ": "vi",
+	"ゔぇ": "ve",
+	"ゔぉ": "vo",
+
+	"しぇ": "sye",
+	"じぇ": "zye",
+	"ちぇ": "tye",
+
+	"つぁ": "tsa",
+	"つぃ": "tsi",
+	"つぇ": "tse",
+	"つぉ": "tso",
+
+	"てぃ": "thi",
+	"とぅ": "twu",
+	"てゅ": "thu",
+
+	"でぃ": "dhi",
+	"どぅ": "dwu",
+	"でゅ": "dhu",
+
+	"ふぁ": "fa",
+	"ふぃ": "fi",
+	"ふぇ": "fe",
+	"ふぉ": "fo",
+	"ふゅ": "fyu",
+}
+
+var nihonYoon = map[string]string{
+	"きゃ": "kya",
+	"きゅ": "kyu",
+	"きょ": "kyo",
+
+	"ぎゃ": "gya",
+	"ぎゅ": "gyu",
+	"ぎょ": "gyo",
+
+	"しゃ": "sya",
+	"しゅ": "syu",
+	"しょ": "syo",
+
+	"じゃ": "zya",
+	"じゅ": "zyu",
+	"じょ": "zyo",
+
+	"ちゃ": "tya",
+	"ちゅ": "tyu",
+	"ちょ": "tyo",
+
+	"ぢゃ": "dya",
+	"ぢゅ": "dyu",
+	"ぢょ": "dyo",
+
+	"にゃ": "nya",
+	"にゅ": "nyu",
+	"にょ": "nyo",
+
+	"ひゃ": "hya",
+	"ひゅ": "hyu",
+	"ひょ": "hyo",
+
+	"びゃ": "bya",
+	"びゅ": "byu",
+	"びょ": "byo",
+
+	"ぴゃ": "pya",
+	"ぴゅ": "pyu",
+	"ぴょ": "pyo",
+
+	"みゃ": "mya",
+	"みゅ": "myu",
+	"みょ": "myo",
+
+	"りゃ": "rya",
+	"りゅ": "ryu",
+	"りょ": "ryo",
+
+	"くゎ": "kwa",
+	"ぐゎ": "gwa",
+
+	// Foreign sounds, mostly in katakana loanwords.
+	"いぇ": "ye",
+
+	"うぃ": "wi",
+	"うぇ": "we",
+	"うぉ": "wo",
+
+	"ゔぁ": "va",
+	"ゔぃ": "vi",
+	"ゔぇ": "ve",
+	"ゔぉ": "vo",
+
+	"しぇ": "sye",
+	"じぇ": "zye",
+	"ちぇ": "tye",
+
+	"つぁ": "tsa",
+	"つぃ": "tsi",
+	"つぇ": "tse",
+	"つぉ": "tso",
+
+	"てぃ": "thi",
+	"とぅ": "twu",
+	"てゅ": "thu",
+
+	"でぃ": "dhi",
+	"どぅ": "dwu",
+	"でゅ": "dhu",
+
+	"ふぁ": "fa",
+	"ふぃ": "fi",
+	"ふぇ": "fe",
+	"ふぉ": "fo",
+	"ふゅ": "fyu",
+}
+
+// wapuroKana and wapuroYoon hold spellings that Hiragana and Katakana
+// translate back to the same kana. There is no such spelling for ぢゃ,
+// ぢゅ and ぢょ, which are written as ぢ followed by a small kana.
+var wapuroKana = map[rune]string{
+	'あ': "a",
+	'い': "i",
+	'う': "u",
+	'え': "e",
+	'お': "o",
+	'か': "ka",
+	'き': "ki",
+	'く': "ku",
+	'け': "ke",
+	'こ': "ko",
+	'が': "ga",
+	'ぎ': "gi",
+	'ぐ': "gu",
+	'げ': "ge",
+	'ご': "go",
+	'さ': "sa",
+	'し': "shi",
+	'す': "su",
+	'せ': "se",
+	'そ': "so",
+	'ざ': "za",
+	'じ': "ji",
+	'ず': "zu",
+	'ぜ': "ze",
+	'ぞ': "zo",
+	'た': "ta",
+	'ち': "chi",
+	'つ': "tsu",
+	'て': "te",
+	'と': "to",
+	'だ': "da",
+	'ぢ': "di",
+	'づ': "du",
+	'で': "de",
+	'ど': "do",
+	'な': "na",
+	'に': "ni",
+	'ぬ': "nu",
+	'ね': "ne",
+	'の': "no",
+	'は': "ha",
+	'ひ': "hi",
+	'ふ': "fu",
+	'へ': "he",
+	'ほ': "ho",
+	'ば': "ba",
+	'び': "bi",
+	'ぶ': "bu",
+	'べ': "be",
+	'ぼ': "bo",
+	'ぱ': "pa",
+	'ぴ': "pi",
+	'ぷ': "pu",
+	'ぺ': "pe",
+	'ぽ': "po",
+	'ま': "ma",
+	'み': "mi",
+	'む': "mu",
+	'め': "me",
+	'も': "mo",
+	'や': "ya",
+	'ゆ': "yu",
+	'よ': "yo",
+	'ら': "ra",
+	'り': "ri",
+	'る': "ru",
+	'れ': "re",
+	'ろ': "ro",
+	'わ': "wa",
+	'ゐ': "wyi",
+	'ゑ': "wye",
+	'を': "wo",
+	'ん': "nn",
+	'ゔ': "vu",
+
+	'ぁ': "xa",
+	'ぃ': "xi",
+	'ぅ': "xu",
+	'ぇ': "xe",
+	'ぉ': "xo",
+	'っ': "xtu",
+	'ゃ': "xya",
+	'ゅ': "xyu",
+	'ょ': "xyo",
+	'ゎ': "xwa",
+	'ゕ': "xka",
+	'ゖ': "xke",
+}
+
+var wapuroYoon = map[string]string{
+	"きゃ": "kya",
+	"きゅ": "kyu",
+	"きょ": "kyo",
+
+	"ぎゃ": "gya",
+	"ぎゅ": "gyu",
+	"ぎょ": "gyo",
+
+	"しゃ": "sha",
+	"しゅ": "shu",
+	"しょ": "sho",
+
+	"じゃ": "ja",
+	"じゅ": "ju",
+	"じょ": "jo",
+
+	"ちゃ": "cha",
+	"ちゅ": "chu",
+	"ちょ": "cho",
+
+	"にゃ": "nya",
+	"にゅ": "nyu",
+	"にょ": "nyo",
+
+	"ひゃ": "hya",
+	"ひゅ": "hyu",
+	"ひょ": "hyo",
+
+	"びゃ": "bya",
+	"びゅ": "byu",
+	"びょ": "byo",
+
+	"ぴゃ": "pya",
+	"ぴゅ": "pyu",
+	"ぴょ": "pyo",
+
+	"みゃ": "mya",
+	"みゅ": "myu",
+	"みょ": "myo",
+
+	"りゃ": "rya",
+	"りゅ": "ryu",
+	"りょ": "ryo",
+}
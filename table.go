@@ -0,0 +1,138 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Table holds the romaji recognized when translating into hiragana and
+// katakana, each with the kana it spells. The kana are written as hiragana
+// and rewritten as katakana when translating into katakana; anything else,
+// such as punctuation, is written as it is. The zero value is an empty table
+// ready to use.
+//
+// An entry may also hold pending input, which replaces the romaji once it
+// is matched and is read again, as in an input method: tt is っ with t
+// pending, so that tta is った.
+type Table struct {
+	entries map[string]entry
+	maxLen  int // The length of the longest romaji.
+}
+
+type entry struct {
+	kana    string
+	pending string
+}
+
+// defaultTable is the table used when KanaConfig.Table is nil.
+var defaultTable = newTable(romajiH)
+
+func newTable(m map[string]string) *Table {
+	t := new(Table)
+	for roman, kana := range m {
+		t.Set(roman, kana, "")
+	}
+	return t
+}
+
+// DefaultTable returns a copy of the built-in table, which holds the romaji
+// of the Microsoft and Google input methods, for changing.
+func DefaultTable() *Table {
+	return newTable(romajiH)
+}
+
+// Set adds romaji to the table, spelling kana, with the pending input, which
+// may be empty. Romaji is matched without regard to case. Pending input
+// must be shorter than the romaji it replaces, or Set panics.
+func (t *Table) Set(romaji, kana, pending string) {
+	if romaji == "" || len(pending) >= len(romaji) {
+		panic("nihongo: bad table entry " + romaji)
+	}
+	if t.entries == nil {
+		t.entries = make(map[string]entry)
+	}
+	t.entries[lowerString(romaji)] = entry{kana, pending}
+	if len(romaji) > t.maxLen {
+		t.maxLen = len(romaji)
+	}
+}
+
+// Delete removes romaji from the table.
+func (t *Table) Delete(romaji string) {
+	delete(t.entries, lowerString(romaji))
+}
+
+// Lookup returns the kana spelled by romaji, and its pending input, and
+// reports whether romaji is in the table.
+func (t *Table) Lookup(romaji string) (kana, pending string, ok bool) {
+	e, ok := t.entries[lowerString(romaji)]
+	return e.kana, e.pending, ok
+}
+
+// match returns the length of the longest romaji in the table at the start
+// of s, which must be lower case, and its entry, or 0 if there is none.
+func (t *Table) match(s string) (int, entry) {
+	n := len(s)
+	if n > t.maxLen {
+		n = t.maxLen
+	}
+	for ; n > 0; n-- {
+		if e, ok := t.entries[s[:n]]; ok {
+			return n, e
+		}
+	}
+	return 0, entry{}
+}
+
+// hasPrefix reports whether s is the start of longer romaji in the table.
+func (t *Table) hasPrefix(s string) bool {
+	for roman := range t.entries {
+		if len(roman) > len(s) && strings.HasPrefix(roman, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// begins reports whether s is the start of, or is, romaji in the table.
+func (t *Table) begins(s string) bool {
+	_, ok := t.entries[s]
+	return ok || t.hasPrefix(s)
+}
+
+// ReadTable reads a table in the format exported by Google Japanese Input:
+// one entry to a line, with the romaji, the kana and, optionally, the pending
+// input separated by tabs. Blank lines are ignored.
+func ReadTable(r io.Reader) (*Table, error) {
+	t := new(Table)
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSuffix(s.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		f := strings.Split(text, "\t")
+		if len(f) == 2 {
+			f = append(f, "")
+		}
+		switch {
+		case len(f) != 3:
+			return nil, fmt.Errorf("nihongo: table line %d: want 2 or 3 fields, have %d", line, len(f))
+		case f[0] == "":
+			return nil, fmt.Errorf("nihongo: table line %d: no romaji", line)
+		case len(f[2]) >= len(f[0]):
+			return nil, fmt.Errorf("nihongo: table line %d: pending input %q not shorter than %q", line, f[2], f[0])
+		}
+		t.Set(f[0], f[1], f[2])
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -5,7 +5,8 @@
 // Package nihongo implements simple transliteration between romaji
 // and the two syllabic Japanese scripts, hiragana and katakana, encoded
 // as UTF-8-encoded Unicode. Romaji output may include injected spaces
-// to separate converted text from unconverted, and other markers.
+// to separate converted text from unconverted, as controlled by
+// RomajiConfig.Boundary, and other markers.
 // Invalid sequences, such as small kanas with no preceding kana,
 // are passed unaltered. Hiragana and katakana may be inaccurate
 // due to false matches. Katakana may be further inaccurate because
@@ -16,6 +17,7 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -79,9 +81,11 @@ type translator struct {
 	put   func(byte)
 	ch    chan byte
 	peekc rune
-	// These are used only when next3 doing the input (hiragana, katakana).
+	// These are used only when lookahead is doing the input (hiragana, katakana).
 	save    []byte
 	runeBuf [utf8.UTFMax]byte
+	offset  int   // Bytes consumed by advance.
+	shifts  []int // Offsets after which the input is one byte longer than what get returned.
 }
 
 func newTranslator(get func() rune, put func(byte), ch chan byte) *translator {
@@ -129,21 +133,65 @@ func (t *translator) putString(s string) {
 	}
 }
 
-func (t *translator) next3() string {
-	for len(t.save) < 3 {
+// lookahead returns the next n bytes of input, or fewer at the end of the input,
+// without consuming them.
+func (t *translator) lookahead(n int) string {
+	for len(t.save) < n {
 		r := t.get()
 		if r == eof {
 			return string(t.save)
 		}
-		n := utf8.EncodeRune(t.runeBuf[:], r)
-		t.save = append(t.save, t.runeBuf[:n]...)
+		w := utf8.EncodeRune(t.runeBuf[:], r)
+		t.save = append(t.save, t.runeBuf[:w]...)
 	}
-	return string(t.save[:3])
+	return string(t.save[:n])
 }
 
 func (t *translator) advance(n int) {
 	copy(t.save, t.save[n:])
 	t.save = t.save[:len(t.save)-n]
+	t.offset += n
+}
+
+// replace consumes the next n bytes of input, which lookahead has returned,
+// and pushes s, which must be shorter, back in their place.
+func (t *translator) replace(n int, s string) {
+	t.advance(n - len(s))
+	copy(t.save, s)
+}
+
+// syllabicN returns the length of an explicit syllabic n, n' or nn, at
+// the start of the lower-case romaji s, or 0 if there is none. Unlike an
+// input method, which reads nn as ん wherever it appears, a doubled n before
+// a vowel or y is read as ん followed by a kana from the n row, so that
+// konnichiha is こんにちは; kon'nichiha and konnnichiha also spell it.
+// A lone n before a consonant or at the end of the input is also ん, as
+// is xn, but those are left to the tables.
+func syllabicN(s string) int {
+	if len(s) < 2 {
+		return 0
+	}
+	switch {
+	case s[0] != 'n':
+		return 0
+	case s[1] == '\'':
+		return 2
+	case s[1] == 'n' && (len(s) < 3 || strings.IndexByte("aiueoy", s[2]) < 0):
+		return 2
+	}
+	return 0
+}
+
+// inputOffset returns the offset in the input of the byte at offset off
+// of what get has returned.
+func (t *translator) inputOffset(off int) int {
+	n := off
+	for _, s := range t.shifts {
+		if s <= off {
+			n++
+		}
+	}
+	return n
 }
 
 func (t *translator) Read(p []byte) (int, error) {
@@ -0,0 +1,251 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// WidenKatakana rewrites the half-width katakana in text, U+FF61 to U+FF9F,
+// as standard katakana and returns the result. A kana followed by a
+// half-width voiced or semi-voiced sound mark becomes the voiced kana:
+// ｶﾞ is ガ and ﾊﾟ is パ. The half-width punctuation becomes its full-width
+// form. Everything else is unchanged.
+func WidenKatakana(text []byte) []byte {
+	var buf bytes.Buffer
+	c := converter{t: newTranslator(bytesGetter(text), bufPutter(&buf), nil), widenOnly: true}
+	c.translate()
+	return buf.Bytes()
+}
+
+// WidenKatakanaString rewrites the half-width katakana in text as standard
+// katakana and returns the result. It converts as WidenKatakana does.
+func WidenKatakanaString(text string) string {
+	var buf bytes.Buffer
+	c := converter{t: newTranslator(stringGetter(text), bufPutter(&buf), nil), widenOnly: true}
+	c.translate()
+	return buf.String()
+}
+
+// WidenKatakanaReader returns an io.Reader that will rewrite the half-width
+// katakana in its input as standard katakana. It converts as WidenKatakana does.
+func WidenKatakanaReader(rd io.Reader) io.Reader {
+	ch := make(chan byte, 100)
+	c := &converter{t: newTranslator(readerGetter(rd), chanPutter(ch), ch), widenOnly: true}
+	go c.translate()
+	return c
+}
+
+// A WidthConfig controls the folding of full-width ASCII and the
+// ideographic space to their narrow forms and back. The zero value is the
+// configuration used by Narrow, Widen and their String and Reader variants.
+type WidthConfig struct {
+	// KeepKatakana leaves the width of katakana unchanged. Otherwise
+	// half-width katakana is widened, as by WidenKatakana, whichever way
+	// the width of ASCII is folded.
+	KeepKatakana bool
+}
+
+// folder implements width folding.
+type folder struct {
+	t    *translator
+	cfg  *WidthConfig
+	wide bool // Fold to full width rather than narrow.
+}
+
+// Narrow folds full-width ASCII and the ideographic space in text to ASCII
+// and returns the result, so Ｔｏｋｙｏ１２３ is Tokyo123.
+func Narrow(text []byte) []byte {
+	return new(WidthConfig).Narrow(text)
+}
+
+// NarrowString folds full-width ASCII and the ideographic space in text to ASCII
+// and returns the result.
+func NarrowString(text string) string {
+	return new(WidthConfig).NarrowString(text)
+}
+
+// NarrowReader returns an io.Reader that will fold full-width ASCII and the
+// ideographic space in its input to ASCII.
+func NarrowReader(rd io.Reader) io.Reader {
+	return new(WidthConfig).NarrowReader(rd)
+}
+
+// Widen folds printable ASCII in text, including the space, to full width
+// and returns the result, so Tokyo 123 is Ｔｏｋｙｏ　１２３.
+func Widen(text []byte) []byte {
+	return new(WidthConfig).Widen(text)
+}
+
+// WidenString folds printable ASCII in text, including the space, to full width
+// and returns the result.
+func WidenString(text string) string {
+	return new(WidthConfig).WidenString(text)
+}
+
+// WidenReader returns an io.Reader that will fold printable ASCII in its input,
+// including the space, to full width.
+func WidenReader(rd io.Reader) io.Reader {
+	return new(WidthConfig).WidenReader(rd)
+}
+
+// Narrow folds full-width ASCII and the ideographic space in text to ASCII
+// as configured by c and returns the result.
+func (c *WidthConfig) Narrow(text []byte) []byte {
+	var buf bytes.Buffer
+	f := folder{newTranslator(bytesGetter(text), bufPutter(&buf), nil), c, false}
+	f.translate()
+	return buf.Bytes()
+}
+
+// NarrowString folds full-width ASCII and the ideographic space in text to ASCII
+// as configured by c and returns the result.
+func (c *WidthConfig) NarrowString(text string) string {
+	var buf bytes.Buffer
+	f := folder{newTranslator(stringGetter(text), bufPutter(&buf), nil), c, false}
+	f.translate()
+	return buf.String()
+}
+
+// NarrowReader returns an io.Reader that will fold full-width ASCII and the
+// ideographic space in its input to ASCII as configured by c.
+func (c *WidthConfig) NarrowReader(rd io.Reader) io.Reader {
+	ch := make(chan byte, 100)
+	f := &folder{newTranslator(readerGetter(rd), chanPutter(ch), ch), c, false}
+	go f.translate()
+	return f
+}
+
+// Widen folds printable ASCII in text, including the space, to full width
+// as configured by c and returns the result.
+func (c *WidthConfig) Widen(text []byte) []byte {
+	var buf bytes.Buffer
+	f := folder{newTranslator(bytesGetter(text), bufPutter(&buf), nil), c, true}
+	f.translate()
+	return buf.Bytes()
+}
+
+// WidenString folds printable ASCII in text, including the space, to full width
+// as configured by c and returns the result.
+func (c *WidthConfig) WidenString(text string) string {
+	var buf bytes.Buffer
+	f := folder{newTranslator(stringGetter(text), bufPutter(&buf), nil), c, true}
+	f.translate()
+	return buf.String()
+}
+
+// WidenReader returns an io.Reader that will fold printable ASCII in its input,
+// including the space, to full width as configured by c.
+func (c *WidthConfig) WidenReader(rd io.Reader) io.Reader {
+	ch := make(chan byte, 100)
+	f := &folder{newTranslator(readerGetter(rd), chanPutter(ch), ch), c, true}
+	go f.translate()
+	return f
+}
+
+func (f *folder) Read(p []byte) (int, error) {
+	return f.t.Read(p)
+}
+
+func (f *folder) translate() {
+	t := f.t
+	if !f.cfg.KeepKatakana {
+		t.get = halfWidthGetter(t.get)
+	}
+	for {
+		r := t.next()
+		if r == eof {
+			break
+		}
+		if f.wide {
+			t.putRune(widen(r))
+		} else {
+			t.putRune(narrow(r))
+		}
+	}
+	if t.ch != nil {
+		close(t.ch)
+	}
+}
+
+// widen returns the full-width form of printable ASCII r, or r itself.
+func widen(r rune) rune {
+	switch {
+	case r == ' ':
+		return '\u3000'
+	case '!' <= r && r <= '~':
+		return r + '！' - '!'
+	}
+	return r
+}
+
+// narrow returns the ASCII form of the full-width r, or r itself.
+func narrow(r rune) rune {
+	switch {
+	case r == '\u3000':
+		return ' '
+	case '！' <= r && r <= '～':
+		return r - ('！' - '!')
+	}
+	return r
+}
+
+const (
+	halfWidthFirst = '｡' // U+FF61
+	halfWidthLast  = 'ﾟ' // U+FF9F
+)
+
+// halfWidth holds the full-width forms of the half-width katakana,
+// in order from U+FF61.
+var halfWidth = []rune("。「」、・ヲァィゥェォャュョッー" +
+	"アイウエオカキクケコサシスセソタチツテトナニヌネノ" +
+	"ハヒフヘホマミムメモヤユヨラリルレロワン゛゜")
+
+// halfWidthGetter returns a getter that reads from get, widening half-width
+// katakana and composing each with a half-width voiced or semi-voiced sound
+// mark that follows it.
+func halfWidthGetter(get func() rune) func() rune {
+	peekc := rune(eof)
+	return func() rune {
+		c := peekc
+		if c == eof {
+			c = get()
+		}
+		peekc = eof
+		if c < halfWidthFirst || halfWidthLast < c {
+			return c
+		}
+		c = halfWidth[c-halfWidthFirst]
+		peekc = get()
+		if v, ok := voice(c, peekc); ok {
+			peekc = eof
+			return v
+		}
+		return c
+	}
+}
+
+// voice returns the katakana r voiced by the half-width sound mark,
+// ﾞ or ﾟ, and reports whether there is such a kana.
+func voice(r, mark rune) (rune, bool) {
+	switch mark {
+	case 'ﾞ':
+		switch {
+		case strings.ContainsRune("カキクケコサシスセソタチツテトハヒフヘホ", r):
+			return r + 1, true
+		case r == 'ウ':
+			return 'ヴ', true
+		case r == 'ワ', r == 'ヲ':
+			return r + 'ヷ' - 'ワ', true
+		}
+	case 'ﾟ':
+		if strings.ContainsRune("ハヒフヘホ", r) {
+			return r + 2, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nihongo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// narrowKatakana maps katakana and Japanese punctuation to their half-width
+// forms, with voiced kana written as two runes.
+var narrowKatakana = func() map[rune]string {
+	m := make(map[rune]string)
+	for i, r := range halfWidth {
+		hw := halfWidthFirst + rune(i)
+		m[r] = string(hw)
+		for _, mark := range "ﾞﾟ" {
+			if v, ok := voice(r, mark); ok {
+				m[v] = string(hw) + string(mark)
+			}
+		}
+	}
+	return m
+}()
+
+// narrowString returns s with its katakana and Japanese punctuation
+// written in half width.
+func narrowString(s string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		if n, ok := narrowKatakana[r]; ok {
+			b.WriteString(n)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// largeKatakana maps the small katakana to the large.
+var largeKatakana = map[rune]rune{
+	'ァ': 'ア',
+	'ィ': 'イ',
+	'ゥ': 'ウ',
+	'ェ': 'エ',
+	'ォ': 'オ',
+	'ヵ': 'カ',
+	'ヶ': 'ケ',
+	'ッ': 'ツ',
+	'ャ': 'ヤ',
+	'ュ': 'ユ',
+	'ョ': 'ヨ',
+	'ヮ': 'ワ',
+}
+
+// zengin holds the characters permitted in Zengin bank transfer records.
+const zengin = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
+	"ｱｲｳｴｵｶｷｸｹｺｻｼｽｾｿﾀﾁﾂﾃﾄﾅﾆﾇﾈﾉﾊﾋﾌﾍﾎﾏﾐﾑﾒﾓﾔﾕﾖﾗﾘﾙﾚﾛﾜｦﾝﾞﾟ" +
+	"\\,.｢｣()-/ "
+
+// A ZenginError lists, in order of appearance, the characters that
+// Zengin could not represent.
+type ZenginError []rune
+
+func (e ZenginError) Error() string {
+	return fmt.Sprintf("nihongo: not in the Zengin character set: %q", string(e))
+}
+
+// Zengin rewrites the katakana in text in half width, as required in
+// Zengin bank transfer records, and returns the result. Voiced kana
+// are written as the kana and a separate sound mark, small kana as the
+// large kana and the prolonged sound mark ー as a hyphen, so ジェーシービー
+// is ｼﾞｴ-ｼ-ﾋﾞ-. Half-width katakana is accepted too. If the result holds
+// characters outside the Zengin character set, which comprises digits,
+// upper-case ASCII letters, half-width katakana and a little punctuation,
+// Zengin returns them, unchanged, in the result and lists them in a
+// ZenginError.
+func Zengin(text string) (string, error) {
+	text = WidenKatakanaString(text)
+	var b bytes.Buffer
+	var bad ZenginError
+	for _, r := range text {
+		if l, ok := largeKatakana[r]; ok {
+			r = l
+		}
+		if r == 'ー' {
+			r = '-'
+		}
+		n, ok := narrowKatakana[r]
+		if !ok {
+			n = string(r)
+		}
+		for _, c := range n {
+			if !strings.ContainsRune(zengin, c) && !strings.ContainsRune(string(bad), c) {
+				bad = append(bad, c)
+			}
+		}
+		b.WriteString(n)
+	}
+	if bad != nil {
+		return b.String(), bad
+	}
+	return b.String(), nil
+}